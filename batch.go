@@ -0,0 +1,178 @@
+package sweep
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// GetMany retrieves values associated with the keys from the sweep,
+// locking each shard once for all of its keys. Values and errors come
+// back in the order of the keys, the error of a key being the one Get
+// would return for it.
+func (s *Sweep) GetMany(keys []string) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+
+	if s.isClosed() {
+		for i := range errs {
+			errs[i] = ErrClosed
+		}
+
+		return values, errs
+	}
+
+	now := time.Now().UnixNano()
+
+	entries := make([]entry.Entry, len(keys))
+	hashedKeys := make([]uint64, len(keys))
+	for i, key := range keys {
+		hashedKeys[i] = s.hashKey(key)
+	}
+
+	for shardIdx, positions := range s.groupByShard(hashedKeys) {
+		s.shards[shardIdx].getMany(hashedKeys, keys, positions, now, entries, errs)
+	}
+
+	for i, e := range entries {
+		if errs[i] != nil {
+			continue
+		}
+
+		s.maybeRefresh(keys[i], e.Timestamp, e.Expiry, now)
+		values[i] = e.Value
+	}
+
+	return values, errs
+}
+
+// PutMany inserts the items into the sweep, locking each shard once for
+// all of its items. Errors come back in the order of the items, the
+// error of an item being the one Put would return for it.
+func (s *Sweep) PutMany(items []Item) []error {
+	errs := make([]error, len(items))
+
+	if s.isClosed() {
+		for i := range errs {
+			errs[i] = ErrClosed
+		}
+
+		return errs
+	}
+
+	now := time.Now()
+
+	entries := make([]entry.Entry, len(items))
+	hashedKeys := make([]uint64, len(items))
+	for i, item := range items {
+		hashedKeys[i] = s.hashKey(item.Key)
+		entries[i] = entry.Entry{
+			HashedKey: hashedKeys[i],
+			Timestamp: now.UnixNano(),
+			Expiry:    unixExpiry(now.Add(s.cfg.EntryLifetime)),
+			Key:       []byte(item.Key),
+			Value:     item.Value,
+		}
+
+		if len(item.Value) > s.cfg.MaxEntrySize {
+			errs[i] = ErrEntryTooLarge
+		}
+	}
+
+	for shardIdx, positions := range s.groupByShard(hashedKeys) {
+		shardAllotted := s.shards[shardIdx]
+
+		s.addEntriesCount(shardAllotted.putMany(entries, positions, errs))
+
+		for _, i := range positions {
+			if errs[i] != nil {
+				shardAllotted.stats.countPutFailure(errs[i])
+			}
+		}
+	}
+
+	return errs
+}
+
+// groupByShard returns positions of the hashed keys grouped by the
+// index of the shard they belong to.
+func (s *Sweep) groupByShard(hashedKeys []uint64) map[uint64][]int {
+	groups := make(map[uint64][]int)
+	for i, hk := range hashedKeys {
+		shardIdx := s.getShardIndex(hk)
+		groups[shardIdx] = append(groups[shardIdx], i)
+	}
+
+	return groups
+}
+
+// getMany looks up the keys at positions under a single read lock,
+// storing their entries and errors at the same positions.
+func (sh *shard) getMany(hashedKeys []uint64, keys []string, positions []int,
+	now int64, entries []entry.Entry, errs []error) {
+
+	if sh.admission != nil {
+		for _, i := range positions {
+			sh.admission.Increment(hashedKeys[i])
+		}
+	}
+
+	// unreferenced holds positions and frame indexes of entries to set
+	// the reference bit of.
+	var unreferenced [][2]int
+
+	sh.mu.RLock()
+	for _, i := range positions {
+		e, idx, referenced, err := sh.lookupLocked(hashedKeys[i], []byte(keys[i]), now)
+		entries[i], errs[i] = e, err
+
+		if err != nil {
+			if err == ErrEntryNotFound {
+				atomic.AddUint64(&sh.stats.misses, 1)
+			}
+
+			continue
+		}
+
+		atomic.AddUint64(&sh.stats.hits, 1)
+
+		if sh.evictionPolicy == EvictCLOCK && !referenced {
+			unreferenced = append(unreferenced, [2]int{i, idx})
+		}
+	}
+	sh.mu.RUnlock()
+
+	if len(unreferenced) == 0 {
+		return
+	}
+
+	sh.mu.Lock()
+	for _, ref := range unreferenced {
+		sh.markReferencedLocked(hashedKeys[ref[0]], ref[1])
+	}
+	sh.mu.Unlock()
+}
+
+// putMany puts the entries at positions which have no error yet under a
+// single write lock, storing errors at the same positions. It returns
+// the change in the number of entries of the shard.
+func (sh *shard) putMany(entries []entry.Entry, positions []int, errs []error) int {
+	added := 0
+
+	sh.mu.Lock()
+	for _, i := range positions {
+		if errs[i] != nil {
+			continue
+		}
+
+		n, err := sh.putLocked(entries[i], nil)
+		added += n
+		errs[i] = err
+	}
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
+
+	sh.notifyRemovals(removed)
+	return added
+}
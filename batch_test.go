@@ -0,0 +1,80 @@
+package sweep
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_PutMany(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 4, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	items := make([]Item, 0, 32)
+	for i := 0; i < 32; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+		items = append(items, Item{Key: key, Value: []byte("valueof" + key)})
+	}
+	items = append(items, Item{Key: "tooLarge", Value: make([]byte, defaultMaxEntrySize+1)})
+
+	errs := cache.PutMany(items)
+	assert.Lenf(t, errs, len(items), "expected %d errors, got %d", len(items), len(errs))
+
+	for i, err := range errs[:32] {
+		assert.NoErrorf(t, err, "put should be successful with key %s", items[i].Key)
+	}
+
+	assert.EqualErrorf(t, errs[32], ErrEntryTooLarge.Error(),
+		"expected err %s, got %s", ErrEntryTooLarge, errs[32])
+	assert.Equalf(t, 32, cache.EntriesCount(), "expected %d entries, got %d", 32, cache.EntriesCount())
+
+	for _, item := range items[:32] {
+		val, err := cache.Get(item.Key)
+		assert.NoErrorf(t, err, "get should be successful for %s", item.Key)
+		assert.Equalf(t, item.Value, val, "expected %s, got %s", item.Value, val)
+	}
+}
+
+func TestSweep_GetMany(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 4, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	keys := make([]string, 0, 32)
+	for i := 0; i < 32; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+		keys = append(keys, key)
+
+		if i%2 == 0 {
+			err := cache.Put(key, []byte("valueof"+key))
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+	}
+
+	values, errs := cache.GetMany(keys)
+	assert.Lenf(t, values, len(keys), "expected %d values, got %d", len(keys), len(values))
+
+	for i, key := range keys {
+		if i%2 != 0 {
+			assert.EqualErrorf(t, errs[i], ErrEntryNotFound.Error(),
+				"expected err %s for %s, got %s", ErrEntryNotFound, key, errs[i])
+			assert.Nilf(t, values[i], "value of %s should be nil", key)
+			continue
+		}
+
+		assert.NoErrorf(t, errs[i], "get should be successful for %s", key)
+		assert.Equalf(t, []byte("valueof"+key), values[i], "expected %s, got %s",
+			"valueof"+key, values[i])
+	}
+
+	stats := cache.Stats()
+	assert.Equalf(t, uint64(16), stats.Hits, "expected %d hits, got %d", 16, stats.Hits)
+	assert.Equalf(t, uint64(16), stats.Misses, "expected %d misses, got %d", 16, stats.Misses)
+
+	cache.Close()
+
+	_, errs = cache.GetMany(keys[:1])
+	assert.EqualErrorf(t, errs[0], ErrClosed.Error(),
+		"expected err %s, got %s", ErrClosed, errs[0])
+}
@@ -64,7 +64,7 @@ func (bb *BipBuffer) Reserve(size int) (int, []byte) {
 				return 0, nil
 			}
 
-			if bb.sizeOfRegionA < size {
+			if bb.idxRegionA < size {
 				return 0, nil
 			}
 
@@ -142,6 +142,18 @@ func (bb *BipBuffer) PeekAt(idx, size int) ([]byte, error) {
 	return bb.buf[idx : idx+size], nil
 }
 
+// RegionA returns the index and the committed bytes of region A,
+// the region holding the oldest data in the buffer.
+func (bb *BipBuffer) RegionA() (int, []byte) {
+	return bb.idxRegionA, bb.buf[bb.idxRegionA : bb.idxRegionA+bb.sizeOfRegionA]
+}
+
+// RegionB returns the index and the committed bytes of region B,
+// the region holding data written after wrapping around.
+func (bb *BipBuffer) RegionB() (int, []byte) {
+	return bb.idxRegionB, bb.buf[bb.idxRegionB : bb.idxRegionB+bb.sizeOfRegionB]
+}
+
 // Capacity returns capacity of the buffer.
 func (bb *BipBuffer) Capacity() int {
 	return cap(bb.buf)
@@ -155,7 +167,26 @@ func (bb *BipBuffer) CommittedSize() int {
 // Grow will increase the underlying buffer size to twice
 // of the current size.
 func (bb *BipBuffer) Grow() {
-	newBuf := make([]byte, 2*cap(bb.buf))
+	bb.resize(2 * cap(bb.buf))
+}
+
+// Shrink will decrease the underlying buffer size to half
+// of the current size, keeping the committed data. It returns
+// false without shrinking if the committed data doesn't fit
+// in half of the buffer.
+func (bb *BipBuffer) Shrink() bool {
+	if bb.CommittedSize() > cap(bb.buf)/2 {
+		return false
+	}
+
+	bb.resize(cap(bb.buf) / 2)
+	return true
+}
+
+// resize moves region A followed by region B to the start of
+// a new buffer of the given size.
+func (bb *BipBuffer) resize(size int) {
+	newBuf := make([]byte, size)
 
 	n := 0
 	for {
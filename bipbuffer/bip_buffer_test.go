@@ -25,6 +25,22 @@ func TestBipBuffer_Reserve(t *testing.T) {
 
 			assert.Equalf(t, requestedSize, len(b), "expected %d, got d", requestedSize, b)
 		})
+
+	t.Run("return nil slice when requested space overlaps region A after wrapping",
+		func(t *testing.T) {
+			bb := New(32)
+			_, _ = bb.Reserve(30)
+			bb.Commit(30)
+			bb.Decommit(8)
+
+			n, b := bb.Reserve(12)
+			assert.Equalf(t, 0, n, "expected index 0, got %d", n)
+			assert.Nil(t, b, "expected nil, got %s", b)
+
+			n, b = bb.Reserve(8)
+			assert.Equalf(t, 0, n, "expected index 0, got %d", n)
+			assert.Equalf(t, 8, len(b), "expected %d, got %d", 8, len(b))
+		})
 }
 
 func TestBipBuffer_Commit(t *testing.T) {
@@ -94,6 +110,39 @@ func TestBipBuffer_Grow(t *testing.T) {
 		"expected capacity %d, got %d", 16, bb.Capacity())
 }
 
+func TestBipBuffer_Shrink(t *testing.T) {
+	bb := New(16)
+
+	t.Run("shrink should keep committed data", func(t *testing.T) {
+		_, b := bb.Reserve(4)
+		copy(b, "pika")
+		bb.Commit(4)
+
+		ok := bb.Shrink()
+		assert.True(t, ok, "shrink should be successful")
+		assert.Equalf(t, 8, bb.Capacity(),
+			"expected capacity %d, got %d", 8, bb.Capacity())
+
+		b, err := bb.PeekAt(0, 4)
+		assert.NoError(t, err, "peek should be successful")
+		assert.Equal(t, []byte("pika"), b, "committed data should be kept")
+	})
+
+	t.Run("shrink should fail if committed data doesn't fit", func(t *testing.T) {
+		_, b := bb.Reserve(4)
+		copy(b, "chu!")
+		bb.Commit(4)
+
+		_, err := bb.PeekAt(4, 4)
+		assert.NoError(t, err, "peek should be successful")
+
+		ok := bb.Shrink()
+		assert.False(t, ok, "shrink should fail")
+		assert.Equalf(t, 8, bb.Capacity(),
+			"expected capacity %d, got %d", 8, bb.Capacity())
+	})
+}
+
 func TestBipBuffer_PeekAt(t *testing.T) {
 	bb := New(64)
 
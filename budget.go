@@ -0,0 +1,58 @@
+package sweep
+
+import "sync/atomic"
+
+// memoryBudget accounts the capacity of the queues of all shards of a
+// sweep against MaxTotalBytes.
+type memoryBudget struct {
+	// used is kept as the first field so it stays aligned for atomic
+	// access on 32-bit platforms.
+	used int64
+
+	// max is the budget in bytes. A zero value means no budget.
+	max int64
+}
+
+// reserve accounts n more bytes if the budget has room for them, and
+// reports whether it did.
+func (b *memoryBudget) reserve(n int) bool {
+	if b.max == 0 {
+		atomic.AddInt64(&b.used, int64(n))
+		return true
+	}
+
+	for {
+		used := atomic.LoadInt64(&b.used)
+		if used+int64(n) > b.max {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&b.used, used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+// add accounts n more bytes, whether the budget has room for them or
+// not. n may be negative.
+func (b *memoryBudget) add(n int) {
+	atomic.AddInt64(&b.used, int64(n))
+}
+
+// grow doubles the capacity of the shard's queue, if the memory budget
+// has room for it.
+func (sh *shard) grow() error {
+	capacity := sh.queue.Capacity()
+	if !sh.budget.reserve(capacity) {
+		return ErrMemoryBudgetExceeded
+	}
+
+	remap, err := sh.queue.Grow()
+	if err != nil {
+		sh.budget.add(-capacity)
+		return err
+	}
+
+	sh.remapIndexes(remap)
+	return nil
+}
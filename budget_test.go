@@ -0,0 +1,75 @@
+package sweep
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweepMemoryBudget(t *testing.T) {
+	t.Run("put should be refused once budget is exceeded", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 2, MaxTotalBytes: 32 * 1024, CleanupInterval: time.Hour})
+		defer cache.Close()
+
+		var err error
+		for i := 0; err == nil; i++ {
+			err = cache.Put(fmt.Sprintf("pokemon%d", i), make([]byte, 256))
+		}
+
+		assert.EqualErrorf(t, err, ErrMemoryBudgetExceeded.Error(),
+			"expected err %s, got %s", ErrMemoryBudgetExceeded, err)
+
+		stats := cache.Stats()
+		assert.Equalf(t, 32*1024, stats.MaxTotalBytes, "expected budget %d, got %d",
+			32*1024, stats.MaxTotalBytes)
+		assert.Truef(t, stats.Capacity <= stats.MaxTotalBytes, "capacity %d should be within budget %d",
+			stats.Capacity, stats.MaxTotalBytes)
+		assert.Truef(t, stats.BytesUsed <= stats.Capacity, "bytes used %d should be within capacity %d",
+			stats.BytesUsed, stats.Capacity)
+		assert.Equalf(t, uint64(1), stats.PutFailures.OverBudget, "expected %d over budget puts, got %d",
+			1, stats.PutFailures.OverBudget)
+	})
+
+	t.Run("hot shard should borrow room from idle shards", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 4, MaxTotalBytes: 64 * 1024, CleanupInterval: time.Hour,
+			EvictionPolicy: EvictFIFO, ShrinkUtilization: 0.25, ShrinkAfterCycles: 1})
+		defer cache.Close()
+
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("pokemon%d", i)
+			if cache.getShardIndex(cache.hashKey(key)) != 0 {
+				continue
+			}
+
+			err := cache.Put(key, make([]byte, 256))
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+
+		stats := cache.Stats()
+		assert.Truef(t, stats.Shards[0].Capacity > 64*1024/4, "hot shard capacity %d should exceed %d",
+			stats.Shards[0].Capacity, 64*1024/4)
+		assert.Truef(t, stats.Capacity <= stats.MaxTotalBytes, "capacity %d should be within budget %d",
+			stats.Capacity, stats.MaxTotalBytes)
+		assert.Truef(t, stats.Evictions > 0, "hot shard should evict once budget is reached")
+
+		// Shrinking the hot shard after it is emptied gives its room
+		// back to the budget.
+		for i := 0; i < 1000; i++ {
+			_ = cache.Delete(fmt.Sprintf("pokemon%d", i))
+		}
+
+		_, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		for i := 0; i < 8; i++ {
+			cache.shrinkUnderutilizedShards(0.25, 1)
+		}
+
+		stats = cache.Stats()
+		assert.Equalf(t, int64(stats.Capacity), cache.budget.used, "expected budget used %d, got %d",
+			stats.Capacity, cache.budget.used)
+		assert.Equalf(t, 4*4*1024, stats.Capacity, "expected capacity %d, got %d", 4*4*1024, stats.Capacity)
+	})
+}
@@ -0,0 +1,69 @@
+package sweep
+
+import (
+	"sync/atomic"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// Compact reclaims the space held by overwritten, deleted and expired
+// entries in every shard, by rewriting live entries into fresh buffers.
+// Shards are compacted one at a time under their write lock.
+func (s *Sweep) Compact() error {
+	if s.isClosed() {
+		return ErrClosed
+	}
+
+	for _, sh := range s.shards {
+		sh.compact()
+	}
+
+	return nil
+}
+
+func (sh *shard) compact() {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.compactLocked()
+}
+
+// compactIfDeadLocked compacts the shard if its dead bytes make up at
+// least ratio of its used bytes, and reports whether it did.
+func (sh *shard) compactIfDeadLocked(ratio float64) bool {
+	if sh.deadBytes == 0 || float64(sh.deadBytes) < ratio*float64(sh.queue.Size()) {
+		return false
+	}
+
+	sh.compactLocked()
+	return true
+}
+
+// compactLocked rewrites the frames the hash index bucket points at
+// into a fresh queue buffer and moves their indexes along.
+func (sh *shard) compactLocked() {
+	sh.queue.Compact(sh.isLiveFrame, func(newIdx int, frame entry.Frame) {
+		// Kept frames passed isLiveFrame, so they are well formed.
+		hk, _ := entry.HashedKeyFromFrame(frame)
+		sh.hashIndexBucket[hk] = newIdx
+	})
+
+	// No tombstone survives compaction.
+	sh.deadBytes = 0
+	atomic.AddUint64(&sh.stats.compactions, 1)
+}
+
+// isLiveFrame reports whether the frame at idx is the current frame of
+// its key, as opposed to a tombstone or an overwritten frame.
+func (sh *shard) isLiveFrame(idx int, frame entry.Frame) bool {
+	if frame.IsTombstone() {
+		return false
+	}
+
+	hk, err := entry.HashedKeyFromFrame(frame)
+	if err != nil {
+		return false
+	}
+
+	return sh.isCurrent(hk, idx)
+}
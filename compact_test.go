@@ -0,0 +1,118 @@
+package sweep
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_Compact(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+
+		err := cache.Put(key, []byte("valueof"+key))
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+
+		err = cache.Put(key, []byte("newvalueof"+key))
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+
+		if i%2 == 0 {
+			err = cache.Delete(key)
+			assert.NoErrorf(t, err, "delete should be successful with key %s", key)
+		}
+	}
+
+	used := cache.Stats().Shards[0].BytesUsed
+
+	err := cache.Compact()
+	assert.NoError(t, err, "compact should be successful")
+
+	stats := cache.Stats()
+	assert.Equalf(t, uint64(1), stats.Compactions, "expected %d compactions, got %d", 1, stats.Compactions)
+	assert.Truef(t, stats.Shards[0].BytesUsed < used/3, "expected less than %d bytes used, got %d",
+		used/3, stats.Shards[0].BytesUsed)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+
+		val, err := cache.Get(key)
+		if i%2 == 0 {
+			assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+				"expected err %s, got %s", ErrEntryNotFound, err)
+			continue
+		}
+
+		assert.NoErrorf(t, err, "get should be successful for %s", key)
+		assert.Equalf(t, []byte("newvalueof"+key), val, "expected %s, got %s", "newvalueof"+key, val)
+	}
+
+	assert.Equalf(t, 50, cache.EntriesCount(), "expected %d entries, got %d", 50, cache.EntriesCount())
+}
+
+func TestSweepCompactionRatio(t *testing.T) {
+	newCache := func(ratio float64) *Sweep {
+		return New(Configuration{ShardsCount: 1, MaxShardSize: 4 * 1024, CleanupInterval: time.Hour,
+			CompactionRatio: ratio})
+	}
+
+	t.Run("full shard should be compacted instead of refusing puts", func(t *testing.T) {
+		cache := newCache(0.5)
+		defer cache.Close()
+
+		val := make([]byte, 256)
+		for i := 0; i < 100; i++ {
+			err := cache.Put(fmt.Sprintf("pokemon%d", i%4), val)
+			assert.NoError(t, err, "put should be successful")
+		}
+
+		assert.Truef(t, cache.Stats().Compactions > 0, "shard should have been compacted")
+		assert.Equalf(t, 4, cache.EntriesCount(), "expected %d entries, got %d", 4, cache.EntriesCount())
+	})
+
+	t.Run("full shard should refuse puts when too little of it is dead", func(t *testing.T) {
+		// Live entries are never dead, so the ratio is never reached.
+		cache := newCache(1)
+		defer cache.Close()
+
+		val := make([]byte, 256)
+		var err error
+		for i := 0; i < 100 && err == nil; i++ {
+			err = cache.Put(fmt.Sprintf("pokemon%d", i%4), val)
+		}
+
+		assert.Error(t, err, "put should fail once the shard is full")
+	})
+
+	t.Run("shard should be compacted only above ratio", func(t *testing.T) {
+		cache := newCache(0.5)
+		defer cache.Close()
+
+		for i := 0; i < 4; i++ {
+			err := cache.Put(fmt.Sprintf("pokemon%d", i), []byte("valueofpokemon"))
+			assert.NoError(t, err, "put should be successful")
+		}
+
+		err := cache.Delete("pokemon3")
+		assert.NoError(t, err, "delete should be successful")
+
+		_, err = cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		compactions := cache.Stats().Compactions
+		assert.Equalf(t, uint64(0), compactions, "expected %d compactions, got %d", 0, compactions)
+
+		err = cache.Delete("pokemon2")
+		assert.NoError(t, err, "delete should be successful")
+
+		_, err = cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		compactions = cache.Stats().Compactions
+		assert.Equalf(t, uint64(1), compactions, "expected %d compactions, got %d", 1, compactions)
+	})
+}
@@ -0,0 +1,124 @@
+package sweep
+
+import (
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// Op is the operation a ComputeFunc asks Compute to apply to its key.
+type Op int
+
+const (
+	// OpKeep leaves the entry of the key as it is.
+	OpKeep Op = iota
+
+	// OpUpdate puts the returned value for the key.
+	OpUpdate
+
+	// OpDelete removes the entry of the key.
+	OpDelete
+)
+
+// ComputeFunc is called by Compute with the current value of the key,
+// and whether the key has a live entry at all. It returns the new value
+// of the key and the operation to apply with it.
+type ComputeFunc func(old []byte, exists bool) (newVal []byte, op Op)
+
+// Compute atomically replaces the value of the key with the one fn
+// computes from it, or deletes the key, and returns the resulting value.
+// The value is nil if the key ends up without an entry.
+//
+// fn runs under the write lock of the key's shard, so it must be quick
+// and must not call into the sweep itself.
+func (s *Sweep) Compute(key string, fn ComputeFunc) ([]byte, error) {
+	if s.isClosed() {
+		return nil, ErrClosed
+	}
+
+	now := time.Now()
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
+	val, op, added, err := shardAllotted.compute(entry.Entry{
+		HashedKey: keyHash,
+		Timestamp: now.UnixNano(),
+		Expiry:    unixExpiry(now.Add(s.cfg.EntryLifetime)),
+		Key:       []byte(key),
+	}, fn)
+
+	s.addEntriesCount(added)
+	if err != nil {
+		if op == OpUpdate {
+			shardAllotted.stats.countPutFailure(err)
+		}
+
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// compute applies fn to the live entry of e's key under the write lock.
+// The entry is put with the value fn returns, if it asks for an update.
+// It returns the resulting value, the operation applied and the change
+// in the number of entries of the shard.
+func (sh *shard) compute(e entry.Entry, fn ComputeFunc) ([]byte, Op, int, error) {
+	sh.mu.Lock()
+	defer sh.unlockAndNotify()
+
+	return sh.computeLocked(e, fn)
+}
+
+func (sh *shard) computeLocked(e entry.Entry, fn ComputeFunc) ([]byte, Op, int, error) {
+	var old []byte
+	exists := false
+
+	_, frame, err := sh.frameOf(e.HashedKey, e.Key)
+	if err == nil {
+		expired, err := entry.ExpiredAt(frame, e.Timestamp)
+		if err != nil {
+			return nil, OpKeep, 0, err
+		}
+
+		if !expired {
+			if old, err = entry.ValFromFrame(frame); err != nil {
+				return nil, OpKeep, 0, err
+			}
+
+			exists = true
+		}
+	} else if err != ErrEntryNotFound {
+		return nil, OpKeep, 0, err
+	}
+
+	newVal, op := fn(old, exists)
+
+	switch op {
+	case OpUpdate:
+		if len(newVal) > sh.maxEntrySize {
+			return nil, op, 0, ErrEntryTooLarge
+		}
+
+		e.Value = newVal
+
+		added, err := sh.putLocked(e, nil)
+		if err != nil {
+			return nil, op, added, err
+		}
+
+		return newVal, op, added, nil
+	case OpDelete:
+		if !exists {
+			return nil, OpKeep, 0, nil
+		}
+
+		if err := sh.delLocked(e.HashedKey, e.Key, e.Timestamp); err != nil {
+			return nil, OpKeep, 0, err
+		}
+
+		return nil, op, -1, nil
+	default:
+		return old, OpKeep, 0, nil
+	}
+}
@@ -0,0 +1,116 @@
+package sweep
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_Compute(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	t.Run("concurrent updates should not be lost", func(t *testing.T) {
+		increment := func(old []byte, exists bool) ([]byte, Op) {
+			next := make([]byte, 8)
+			if exists {
+				binary.LittleEndian.PutUint64(next, binary.LittleEndian.Uint64(old)+1)
+			} else {
+				binary.LittleEndian.PutUint64(next, 1)
+			}
+
+			return next, OpUpdate
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 50; j++ {
+					_, err := cache.Compute("counter", increment)
+					assert.NoError(t, err, "compute should be successful")
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		val, err := cache.Get("counter")
+		assert.NoError(t, err, "get should be successful for counter")
+		assert.Equalf(t, uint64(400), binary.LittleEndian.Uint64(val), "expected %d, got %d",
+			400, binary.LittleEndian.Uint64(val))
+	})
+
+	t.Run("keep should leave entry as it is", func(t *testing.T) {
+		val, err := cache.Compute("pikachu", func(old []byte, exists bool) ([]byte, Op) {
+			assert.False(t, exists, "pikachu should not exist")
+			return []byte("valueofpikachu"), OpKeep
+		})
+		assert.NoError(t, err, "compute should be successful")
+		assert.Nil(t, val, "value should be nil for a missing key")
+
+		_, err = cache.Get("pikachu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+
+	t.Run("delete should remove entry", func(t *testing.T) {
+		err := cache.Put("raichu", []byte("valueofraichu"))
+		assert.NoError(t, err, "put should be successful with key raichu")
+
+		count := cache.EntriesCount()
+
+		val, err := cache.Compute("raichu", func(old []byte, exists bool) ([]byte, Op) {
+			assert.True(t, exists, "raichu should exist")
+			assert.Equalf(t, []byte("valueofraichu"), old, "expected %s, got %s", "valueofraichu", old)
+			return nil, OpDelete
+		})
+		assert.NoError(t, err, "compute should be successful")
+		assert.Nil(t, val, "value should be nil for a deleted key")
+
+		_, err = cache.Get("raichu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+		assert.Equalf(t, count-1, cache.EntriesCount(), "expected %d entries, got %d",
+			count-1, cache.EntriesCount())
+	})
+
+	t.Run("panicking function should not leave shard locked", func(t *testing.T) {
+		func() {
+			defer func() {
+				assert.NotNil(t, recover(), "compute should panic")
+			}()
+
+			_, _ = cache.Compute("pikachu", func(old []byte, exists bool) ([]byte, Op) {
+				panic("compute failed")
+			})
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			err := cache.Put("raichu", []byte("valueofraichu"))
+			assert.NoError(t, err, "put should be successful with key raichu")
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("put should not block after a panicking compute")
+		}
+	})
+
+	t.Run("too large value should not be put", func(t *testing.T) {
+		_, err := cache.Compute("bulbasaur", func(old []byte, exists bool) ([]byte, Op) {
+			return make([]byte, defaultMaxEntrySize+1), OpUpdate
+		})
+		assert.EqualErrorf(t, err, ErrEntryTooLarge.Error(),
+			"expected err %s, got %s", ErrEntryTooLarge, err)
+	})
+}
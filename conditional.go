@@ -0,0 +1,57 @@
+package sweep
+
+import "time"
+
+// PutIfAbsent inserts the value associated with the key into the sweep
+// unless the key already has a live entry. It reports whether the value
+// was put.
+func (s *Sweep) PutIfAbsent(key string, value []byte) (bool, error) {
+	return s.putIf(key, value, func(exists bool, _ uint64) bool {
+		return !exists
+	})
+}
+
+// Replace overwrites the value associated with the key only if the key
+// has a live entry. It reports whether the value was put.
+func (s *Sweep) Replace(key string, value []byte) (bool, error) {
+	return s.putIf(key, value, func(exists bool, _ uint64) bool {
+		return exists
+	})
+}
+
+// CompareAndSwap overwrites the value associated with the key only if
+// the key's live entry is still at version, as returned by
+// GetWithVersion. It reports whether the value was put.
+func (s *Sweep) CompareAndSwap(key string, version uint64, value []byte) (bool, error) {
+	return s.putIf(key, value, func(exists bool, current uint64) bool {
+		return exists && current == version
+	})
+}
+
+// GetWithVersion retrieves value associated with the key from the
+// sweep, together with the version of its entry. The version changes on
+// every write of the key.
+func (s *Sweep) GetWithVersion(key string) ([]byte, uint64, error) {
+	if s.isClosed() {
+		return nil, 0, ErrClosed
+	}
+
+	now := time.Now().UnixNano()
+
+	e, err := s.getEntry(key, now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.maybeRefresh(key, e.Timestamp, e.Expiry, now)
+	return e.Value, e.Version, nil
+}
+
+func (s *Sweep) putIf(key string, value []byte, cond putCondition) (bool, error) {
+	err := s.putUntil(key, value, time.Now().Add(s.cfg.EntryLifetime), cond)
+	if err == errNotApplied {
+		return false, nil
+	}
+
+	return err == nil, err
+}
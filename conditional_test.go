@@ -0,0 +1,151 @@
+package sweep
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_PutIfAbsent(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	t.Run("only one of concurrent puts should apply", func(t *testing.T) {
+		var applied int32
+		var wg sync.WaitGroup
+
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				ok, err := cache.PutIfAbsent("pikachu", []byte("valueofpikachu"))
+				assert.NoError(t, err, "put if absent should be successful")
+				if ok {
+					atomic.AddInt32(&applied, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+		assert.Equalf(t, int32(1), applied, "expected %d applied puts, got %d", 1, applied)
+	})
+
+	t.Run("expired entry should count as absent", func(t *testing.T) {
+		err := cache.PutWithTTL("raichu", []byte("valueofraichu"), 50*time.Millisecond)
+		assert.NoError(t, err, "put should be successful with key raichu")
+
+		time.Sleep(100 * time.Millisecond)
+
+		ok, err := cache.PutIfAbsent("raichu", []byte("newvalueofraichu"))
+		assert.NoError(t, err, "put if absent should be successful")
+		assert.True(t, ok, "put if absent should apply over an expired entry")
+
+		val, err := cache.Get("raichu")
+		assert.NoError(t, err, "get should be successful for raichu")
+		assert.Equalf(t, []byte("newvalueofraichu"), val, "expected %s, got %s",
+			"newvalueofraichu", val)
+	})
+}
+
+func TestSweep_Replace(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	ok, err := cache.Replace("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "replace should be successful")
+	assert.False(t, ok, "replace should not apply to a missing key")
+
+	_, err = cache.Get("pikachu")
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+
+	err = cache.Put("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "put should be successful with key pikachu")
+
+	ok, err = cache.Replace("pikachu", []byte("newvalueofpikachu"))
+	assert.NoError(t, err, "replace should be successful")
+	assert.True(t, ok, "replace should apply to a present key")
+
+	val, err := cache.Get("pikachu")
+	assert.NoError(t, err, "get should be successful for pikachu")
+	assert.Equalf(t, []byte("newvalueofpikachu"), val, "expected %s, got %s",
+		"newvalueofpikachu", val)
+}
+
+func TestSweep_CompareAndSwap(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	t.Run("stale version should not apply", func(t *testing.T) {
+		err := cache.Put("pikachu", []byte("valueofpikachu"))
+		assert.NoError(t, err, "put should be successful with key pikachu")
+
+		_, version, err := cache.GetWithVersion("pikachu")
+		assert.NoError(t, err, "get with version should be successful")
+
+		ok, err := cache.CompareAndSwap("pikachu", version, []byte("newvalueofpikachu"))
+		assert.NoError(t, err, "compare and swap should be successful")
+		assert.True(t, ok, "compare and swap should apply at current version")
+
+		ok, err = cache.CompareAndSwap("pikachu", version, []byte("stalevalueofpikachu"))
+		assert.NoError(t, err, "compare and swap should be successful")
+		assert.False(t, ok, "compare and swap should not apply at stale version")
+
+		val, newVersion, err := cache.GetWithVersion("pikachu")
+		assert.NoError(t, err, "get with version should be successful")
+		assert.Equalf(t, []byte("newvalueofpikachu"), val, "expected %s, got %s",
+			"newvalueofpikachu", val)
+		assert.NotEqual(t, version, newVersion, "version should change on write")
+
+		ok, err = cache.CompareAndSwap("raichu", 0, []byte("valueofraichu"))
+		assert.NoError(t, err, "compare and swap should be successful")
+		assert.False(t, ok, "compare and swap should not apply to a missing key")
+	})
+
+	t.Run("concurrent increments should not be lost", func(t *testing.T) {
+		counter := make([]byte, 8)
+		err := cache.Put("counter", counter)
+		assert.NoError(t, err, "put should be successful with key counter")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 50; j++ {
+					for {
+						val, version, err := cache.GetWithVersion("counter")
+						if !assert.NoError(t, err, "get with version should be successful") {
+							return
+						}
+
+						next := make([]byte, 8)
+						binary.LittleEndian.PutUint64(next, binary.LittleEndian.Uint64(val)+1)
+
+						ok, err := cache.CompareAndSwap("counter", version, next)
+						if !assert.NoError(t, err, "compare and swap should be successful") {
+							return
+						}
+
+						if ok {
+							break
+						}
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		val, err := cache.Get("counter")
+		assert.NoError(t, err, "get should be successful for counter")
+		assert.Equalf(t, uint64(400), binary.LittleEndian.Uint64(val), "expected %d, got %d",
+			400, binary.LittleEndian.Uint64(val))
+	})
+}
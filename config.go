@@ -9,11 +9,59 @@ const (
 
 	defaultShardSize = 4 * 1024 // 4KB
 
-	defaultEntryLifeTime = 10 // minutes
+	defaultEntryLifeTime = 10 * time.Minute
 
-	defaultCleanupInterval = 1 // minute
+	defaultCleanupInterval = 1 * time.Minute
 
 	defaultMaxEntrySize = 1024 // bytes
+
+	minSketchWidth = 64 // counters
+
+	maxSketchWidth = 64 * 1024 // counters
+
+	defaultRefreshWorkers = 4
+
+	refreshQueueSizePerWorker = 64
+
+	defaultCompactionRatio = 0.5
+
+	defaultShrinkAfterCycles = 3
+)
+
+// EvictionPolicy decides how a shard makes room for a new entry once
+// it has reached MaxShardSize, or growing it would exceed MaxTotalBytes.
+type EvictionPolicy int
+
+const (
+	// NoEviction makes Put fail when the shard an entry belongs to
+	// has reached MaxShardSize or MaxTotalBytes.
+	NoEviction EvictionPolicy = iota
+
+	// EvictFIFO evicts the oldest entries of a shard until the new
+	// entry fits.
+	EvictFIFO
+
+	// EvictCLOCK approximates LRU with the CLOCK algorithm. Reading an
+	// entry sets a reference bit in its frame. Eviction moves entries
+	// with the bit set to the back of the queue with the bit cleared,
+	// and evicts the oldest entry without it.
+	EvictCLOCK
+)
+
+// AdmissionPolicy decides whether a new entry may evict entries of
+// a shard which reached MaxShardSize.
+type AdmissionPolicy int
+
+const (
+	// AdmitAll lets every new entry evict older ones.
+	AdmitAll AdmissionPolicy = iota
+
+	// AdmitTinyLFU keeps a count-min sketch of how often keys are
+	// read and written, with counters halved periodically. A new
+	// entry may evict only if its key is more frequent than the key
+	// of the next eviction victim, otherwise Put fails with
+	// ErrEntryRejected.
+	AdmitTinyLFU
 )
 
 type Configuration struct {
@@ -28,7 +76,15 @@ type Configuration struct {
 	// value. A zero value means no restriction on shard size.
 	MaxShardSize int
 
-	// EntryLifetime represents lifetime of an Entry in the sweep.
+	// MaxTotalBytes represents the upper bound limit of the capacity of
+	// all shards together in bytes. Shards grow on demand while the
+	// budget has room, so a busy shard may use room idle shards don't
+	// need. Every shard starts with a small capacity of its own, which
+	// is accounted even past the budget. A zero value means no budget.
+	MaxTotalBytes int
+
+	// EntryLifetime represents lifetime of an Entry put in the sweep
+	// without its own lifetime.
 	EntryLifetime time.Duration
 
 	// MaxEntrySize represents maximum size of Entry in bytes
@@ -38,6 +94,69 @@ type Configuration struct {
 	// CleanupInterval represents the waiting period between cleanup
 	// cycles in sweep.
 	CleanupInterval time.Duration
+
+	// EvictionPolicy represents how a shard which reached MaxShardSize
+	// or MaxTotalBytes makes room for new entries. It has no effect
+	// when both are zero. Defaults to NoEviction.
+	EvictionPolicy EvictionPolicy
+
+	// AdmissionPolicy represents which new entries are allowed to
+	// evict entries of a full shard. It has no effect when
+	// EvictionPolicy is NoEviction. Defaults to AdmitAll.
+	AdmissionPolicy AdmissionPolicy
+
+	// OnRemove, if set, is called for every entry leaving the sweep
+	// with the reason it left. It is called after the shard lock is
+	// released, so it may use the sweep, but it runs on the goroutine
+	// which removed the entry: Put, Delete or the cleanup loop.
+	OnRemove func(key string, value []byte, reason RemoveReason)
+
+	// Loader represents the source GetOrLoad loads missing keys from.
+	Loader Loader
+
+	// LoaderErrorTTL represents how long a failed load of a key is
+	// remembered. GetOrLoad returns the remembered error instead of
+	// loading the key again. A zero value means errors are not
+	// remembered.
+	LoaderErrorTTL time.Duration
+
+	// RefreshAheadRatio represents the fraction of an entry's lifetime
+	// after which a Get hitting the entry reloads it in the background
+	// with Loader, while the current value keeps being served. It
+	// should lie between 0 and 1. A zero value, or no Loader, disables
+	// refresh-ahead.
+	RefreshAheadRatio float64
+
+	// RefreshWorkers represents the number of goroutines reloading
+	// entries in the background.
+	RefreshWorkers int
+
+	// StaleGracePeriod represents how long expired entries are kept
+	// around after their lifetime ends. Get never returns them, but
+	// GetStale does, and GetOrLoad serves them when reloading fails.
+	// With refresh-ahead enabled, GetOrLoad serves them right away
+	// while reloading them in the background. A zero value disables
+	// stale reads.
+	StaleGracePeriod time.Duration
+
+	// CompactionRatio represents the fraction of a shard's used bytes
+	// held by overwritten, deleted or expired entries from which the
+	// shard is compacted, rewriting its live entries into a fresh
+	// buffer. Cleanup compacts shards past it, and so does a full shard
+	// before evicting anything. It should lie in (0, 1]. Compaction
+	// can't be turned off, a zero or out of range value means 0.5.
+	CompactionRatio float64
+
+	// ShrinkUtilization represents the fraction of a shard's capacity
+	// in use below which the shard counts as underutilized. A shard
+	// underutilized for ShrinkAfterCycles cleanups in a row gets its
+	// capacity halved. It should lie between 0 and 0.5. A zero value
+	// disables shrinking.
+	ShrinkUtilization float64
+
+	// ShrinkAfterCycles represents the number of cleanups in a row a
+	// shard has to stay underutilized for before it is shrunk.
+	ShrinkAfterCycles int
 }
 
 func setupVacantDefaultsInConfig(cfg Configuration) Configuration {
@@ -59,6 +178,10 @@ func setupVacantDefaultsInConfig(cfg Configuration) Configuration {
 		}
 	}
 
+	if cfg.MaxTotalBytes < 0 {
+		cfg.MaxTotalBytes = 0
+	}
+
 	if cfg.EntryLifetime == 0 {
 		cfg.EntryLifetime = defaultEntryLifeTime
 	}
@@ -71,6 +194,26 @@ func setupVacantDefaultsInConfig(cfg Configuration) Configuration {
 		cfg.MaxEntrySize = defaultMaxEntrySize
 	}
 
+	if cfg.RefreshAheadRatio < 0 || cfg.RefreshAheadRatio >= 1 {
+		cfg.RefreshAheadRatio = 0
+	}
+
+	if cfg.RefreshWorkers <= 0 {
+		cfg.RefreshWorkers = defaultRefreshWorkers
+	}
+
+	if cfg.CompactionRatio <= 0 || cfg.CompactionRatio > 1 {
+		cfg.CompactionRatio = defaultCompactionRatio
+	}
+
+	if cfg.ShrinkUtilization < 0 || cfg.ShrinkUtilization >= 0.5 {
+		cfg.ShrinkUtilization = 0
+	}
+
+	if cfg.ShrinkAfterCycles <= 0 {
+		cfg.ShrinkAfterCycles = defaultShrinkAfterCycles
+	}
+
 	return cfg
 }
 
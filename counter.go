@@ -0,0 +1,125 @@
+package sweep
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// counterSize is the size of counter values, a little endian int64.
+const counterSize = 8
+
+// Incr atomically adds delta to the 8-byte little endian counter stored
+// for the key and returns the new value. A missing key is created with
+// delta as its value and the configured EntryLifetime. An existing
+// counter is updated in place and keeps its expiry.
+func (s *Sweep) Incr(key string, delta int64) (int64, error) {
+	if s.isClosed() {
+		return 0, ErrClosed
+	}
+
+	now := time.Now()
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
+	n, created, added, err := shardAllotted.incr(keyHash, []byte(key), delta, now.UnixNano(),
+		unixExpiry(now.Add(s.cfg.EntryLifetime)))
+
+	if !created {
+		return n, err
+	}
+
+	s.addEntriesCount(added)
+	if err != nil {
+		shardAllotted.stats.countPutFailure(err)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Decr atomically subtracts delta from the 8-byte little endian counter
+// stored for the key and returns the new value. It behaves like Incr
+// otherwise.
+func (s *Sweep) Decr(key string, delta int64) (int64, error) {
+	return s.Incr(key, -delta)
+}
+
+// incr adds delta to the counter of the key in place under the write
+// lock, or puts a new counter expiring at expiry if the key has no live
+// entry at unix time now in nanoseconds. It reports whether a new
+// counter was put, and the change in the number of entries of the
+// shard putting it caused.
+func (sh *shard) incr(hashedKey uint64, key []byte, delta, now, expiry int64) (int64, bool, int, error) {
+	sh.mu.Lock()
+	n, created, added, err := sh.incrLocked(hashedKey, key, delta, now, expiry)
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
+
+	sh.notifyRemovals(removed)
+	return n, created, added, err
+}
+
+func (sh *shard) incrLocked(hashedKey uint64, key []byte, delta, now, expiry int64) (int64, bool, int, error) {
+	_, frame, err := sh.frameOf(hashedKey, key)
+	if err != nil && err != ErrEntryNotFound {
+		return 0, false, 0, err
+	}
+
+	if err == nil {
+		expired, err := entry.ExpiredAt(frame, now)
+		if err != nil {
+			return 0, false, 0, err
+		}
+
+		if !expired {
+			if sh.admission != nil {
+				sh.admission.Increment(hashedKey)
+			}
+
+			return sh.incrFrame(frame, delta)
+		}
+	}
+
+	if counterSize > sh.maxEntrySize {
+		return 0, true, 0, ErrEntryTooLarge
+	}
+
+	val := make([]byte, counterSize)
+	binary.LittleEndian.PutUint64(val, uint64(delta))
+
+	added, err := sh.putLocked(entry.Entry{
+		HashedKey: hashedKey,
+		Timestamp: now,
+		Expiry:    expiry,
+		Key:       key,
+		Value:     val,
+	}, nil)
+
+	return delta, true, added, err
+}
+
+// incrFrame adds delta to the counter value of the frame in place.
+func (sh *shard) incrFrame(frame entry.Frame, delta int64) (int64, bool, int, error) {
+	val, err := entry.ValView(frame)
+	if err != nil {
+		return 0, false, 0, err
+	}
+
+	if len(val) != counterSize {
+		return 0, false, 0, ErrNotCounter
+	}
+
+	n := int64(binary.LittleEndian.Uint64(val)) + delta
+	binary.LittleEndian.PutUint64(val, uint64(n))
+
+	sh.version++
+	frame.SetVersion(sh.version)
+
+	if sh.evictionPolicy == EvictCLOCK {
+		frame.MarkReferenced()
+	}
+
+	return n, false, 0, nil
+}
@@ -0,0 +1,88 @@
+package sweep
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_Incr(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	t.Run("missing key should be created", func(t *testing.T) {
+		n, err := cache.Incr("pikachu", 5)
+		assert.NoError(t, err, "incr should be successful")
+		assert.Equalf(t, int64(5), n, "expected %d, got %d", 5, n)
+
+		n, err = cache.Decr("pikachu", 7)
+		assert.NoError(t, err, "decr should be successful")
+		assert.Equalf(t, int64(-2), n, "expected %d, got %d", -2, n)
+
+		assert.Equalf(t, 1, cache.EntriesCount(), "expected %d entries, got %d",
+			1, cache.EntriesCount())
+	})
+
+	t.Run("counter should be updated in place", func(t *testing.T) {
+		_, err := cache.Incr("raichu", 1)
+		assert.NoError(t, err, "incr should be successful")
+
+		used := cache.shards[0].usage().BytesUsed
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					_, err := cache.Incr("raichu", 1)
+					assert.NoError(t, err, "incr should be successful")
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		n, err := cache.Incr("raichu", 0)
+		assert.NoError(t, err, "incr should be successful")
+		assert.Equalf(t, int64(801), n, "expected %d, got %d", 801, n)
+
+		assert.Equalf(t, used, cache.shards[0].usage().BytesUsed,
+			"expected %d bytes used, got %d", used, cache.shards[0].usage().BytesUsed)
+	})
+
+	t.Run("incr of existing counter should not allocate", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = cache.Incr("raichu", 1)
+		})
+		assert.Equalf(t, float64(0), allocs, "expected %d allocations, got %f", 0, allocs)
+	})
+
+	t.Run("value which isn't a counter should be rejected", func(t *testing.T) {
+		err := cache.Put("bulbasaur", []byte("valueofbulbasaur"))
+		assert.NoError(t, err, "put should be successful with key bulbasaur")
+
+		_, err = cache.Incr("bulbasaur", 1)
+		assert.EqualErrorf(t, err, ErrNotCounter.Error(),
+			"expected err %s, got %s", ErrNotCounter, err)
+	})
+
+	t.Run("counter larger than max entry size should not be created", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour, MaxEntrySize: 4})
+		defer cache.Close()
+
+		_, err := cache.Incr("pikachu", 1)
+		assert.EqualErrorf(t, err, ErrEntryTooLarge.Error(),
+			"expected err %s, got %s", ErrEntryTooLarge, err)
+
+		_, err = cache.Get("pikachu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		tooLarge := cache.Stats().PutFailures.TooLarge
+		assert.Equalf(t, uint64(1), tooLarge, "expected %d too large puts, got %d", 1, tooLarge)
+	})
+}
@@ -12,3 +12,40 @@ var ErrEntryNotFound = errors.New("entry not found")
 // ErrEntryTooLarge is the error returned when an entry is too large
 // going to be put in sweep.
 var ErrEntryTooLarge = errors.New("entry is too large in size")
+
+// ErrInvalidTTL is the error returned when an entry is put with
+// a lifetime which doesn't end in the future.
+var ErrInvalidTTL = errors.New("entry lifetime must end in the future")
+
+// ErrEntryRejected is the error returned when the admission policy
+// doesn't let an entry evict others to make room for itself.
+var ErrEntryRejected = errors.New("entry rejected by admission policy")
+
+// ErrInvalidSnapshot is the error returned when a snapshot being loaded
+// is malformed, truncated or fails its checksum.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrSnapshotVersion is the error returned when a snapshot being loaded
+// was written in a version this sweep doesn't understand.
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+// ErrNoLoader is the error returned by GetOrLoad when sweep isn't
+// configured with a Loader.
+var ErrNoLoader = errors.New("no loader configured")
+
+// ErrLoaderPanicked is the error returned by GetOrLoad to callers
+// waiting on a load of the key which panicked.
+var ErrLoaderPanicked = errors.New("loader panicked")
+
+// errNotApplied is the error returned by a shard when the condition of
+// a conditional write doesn't hold.
+var errNotApplied = errors.New("write condition not met")
+
+// ErrNotCounter is the error returned by Incr and Decr when the value of
+// the key isn't an 8-byte counter.
+var ErrNotCounter = errors.New("value is not an 8-byte counter")
+
+// ErrMemoryBudgetExceeded is the error returned when an entry doesn't
+// fit without exceeding MaxTotalBytes, and the eviction policy doesn't
+// allow making room for it.
+var ErrMemoryBudgetExceeded = errors.New("memory budget exceeded")
@@ -0,0 +1,112 @@
+package sweep
+
+import (
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// NoExpiration is the remaining lifetime reported for entries which
+// never expire.
+const NoExpiration time.Duration = -1
+
+// EntryInfo describes an entry stored in the sweep.
+type EntryInfo struct {
+	// InsertedAt is the time the entry was put at.
+	InsertedAt time.Time
+
+	// ExpiresAt is the time the entry expires at. It is the zero time
+	// for entries which never expire.
+	ExpiresAt time.Time
+
+	// TTL is the remaining lifetime of the entry, or NoExpiration for
+	// entries which never expire.
+	TTL time.Duration
+
+	// Size is the number of bytes the entry occupies in its shard.
+	Size int
+}
+
+// GetWithInfo retrieves value associated with the key from the sweep,
+// together with information about its entry.
+func (s *Sweep) GetWithInfo(key string) ([]byte, EntryInfo, error) {
+	if s.isClosed() {
+		return nil, EntryInfo{}, ErrClosed
+	}
+
+	now := time.Now()
+
+	e, err := s.getEntry(key, now.UnixNano())
+	if err != nil {
+		return nil, EntryInfo{}, err
+	}
+
+	s.maybeRefresh(key, e.Timestamp, e.Expiry, now.UnixNano())
+	return e.Value, newEntryInfo(key, e, now), nil
+}
+
+// TTL returns the remaining lifetime of the entry of the key, or
+// NoExpiration if it never expires.
+//
+// Unlike reads, it doesn't count as an access of the entry.
+func (s *Sweep) TTL(key string) (time.Duration, error) {
+	if s.isClosed() {
+		return 0, ErrClosed
+	}
+
+	now := time.Now()
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
+	expiry, err := shardAllotted.expiry(keyHash, []byte(key), now.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	if expiry == 0 {
+		return NoExpiration, nil
+	}
+
+	return time.Unix(0, expiry).Sub(now), nil
+}
+
+// Expire sets the remaining lifetime of the entry of the key to d. Like
+// PutWithTTL, lifetimes ending past the year 2262 end in 2262.
+func (s *Sweep) Expire(key string, d time.Duration) error {
+	if d <= 0 {
+		return ErrInvalidTTL
+	}
+
+	return s.setExpiry(key, unixExpiry(time.Now().Add(d)))
+}
+
+// Persist makes the entry of the key never expire.
+func (s *Sweep) Persist(key string) error {
+	return s.setExpiry(key, 0)
+}
+
+func (s *Sweep) setExpiry(key string, expiry int64) error {
+	if s.isClosed() {
+		return ErrClosed
+	}
+
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
+	return shardAllotted.setExpiry(keyHash, []byte(key), expiry, time.Now().UnixNano())
+}
+
+func newEntryInfo(key string, e entry.Entry, now time.Time) EntryInfo {
+	info := EntryInfo{
+		InsertedAt: time.Unix(0, e.Timestamp),
+		TTL:        NoExpiration,
+		Size:       entry.FrameLen([]byte(key), e.Value),
+	}
+
+	if e.Expiry != 0 {
+		info.ExpiresAt = time.Unix(0, e.Expiry)
+		info.TTL = info.ExpiresAt.Sub(now)
+	}
+
+	return info
+}
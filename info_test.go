@@ -0,0 +1,137 @@
+package sweep
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_GetWithInfo(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	before := time.Now()
+	err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), time.Hour)
+	assert.NoError(t, err, "put should be successful with key pikachu")
+
+	after := time.Now()
+
+	val, info, err := cache.GetWithInfo("pikachu")
+	assert.NoError(t, err, "get with info should be successful")
+	assert.Equalf(t, []byte("valueofpikachu"), val, "expected %s, got %s", "valueofpikachu", val)
+	assert.Falsef(t, info.InsertedAt.Before(before), "inserted at %s should not be before %s",
+		info.InsertedAt, before)
+	assert.Falsef(t, info.InsertedAt.After(after), "inserted at %s should not be after %s",
+		info.InsertedAt, after)
+	assert.Falsef(t, info.ExpiresAt.Before(before.Add(time.Hour)) || info.ExpiresAt.After(after.Add(time.Hour)),
+		"expires at %s should be an hour after put", info.ExpiresAt)
+	assert.Truef(t, info.TTL > 0 && info.TTL <= time.Hour, "unexpected ttl %s", info.TTL)
+
+	size := entry.FrameLen([]byte("pikachu"), []byte("valueofpikachu"))
+	assert.Equalf(t, size, info.Size, "expected size %d, got %d", size, info.Size)
+
+	_, _, err = cache.GetWithInfo("raichu")
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+}
+
+func TestSweep_Expire(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	t.Run("expire should shorten the lifetime of entry", func(t *testing.T) {
+		err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), time.Hour)
+		assert.NoError(t, err, "put should be successful with key pikachu")
+
+		err = cache.Expire("pikachu", 50*time.Millisecond)
+		assert.NoError(t, err, "expire should be successful")
+
+		ttl, err := cache.TTL("pikachu")
+		assert.NoError(t, err, "ttl should be successful")
+		assert.Truef(t, ttl > 0 && ttl <= 50*time.Millisecond, "unexpected ttl %s", ttl)
+
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = cache.Get("pikachu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		err = cache.Expire("pikachu", time.Hour)
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expired entry should not be revived, got %s", err)
+	})
+
+	t.Run("persist should make entry never expire", func(t *testing.T) {
+		err := cache.PutWithTTL("raichu", []byte("valueofraichu"), 50*time.Millisecond)
+		assert.NoError(t, err, "put should be successful with key raichu")
+
+		err = cache.Persist("raichu")
+		assert.NoError(t, err, "persist should be successful")
+
+		time.Sleep(100 * time.Millisecond)
+
+		n, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+		assert.Equalf(t, 1, n, "expected %d expired entries, got %d", 1, n)
+
+		ttl, err := cache.TTL("raichu")
+		assert.NoError(t, err, "ttl should be successful")
+		assert.Equalf(t, NoExpiration, ttl, "expected ttl %s, got %s", NoExpiration, ttl)
+	})
+
+	t.Run("lifetime past the year 2262 should not overflow", func(t *testing.T) {
+		err := cache.Put("charmander", []byte("valueofcharmander"))
+		assert.NoError(t, err, "put should be successful with key charmander")
+
+		err = cache.Expire("charmander", math.MaxInt64)
+		assert.NoError(t, err, "expire should be successful")
+
+		ttl, err := cache.TTL("charmander")
+		assert.NoError(t, err, "ttl should be successful")
+		assert.Truef(t, ttl > 0, "unexpected ttl %s", ttl)
+
+		_, err = cache.Get("charmander")
+		assert.NoError(t, err, "get should be successful")
+	})
+
+	t.Run("non positive lifetime should be rejected", func(t *testing.T) {
+		err := cache.Expire("raichu", 0)
+		assert.EqualErrorf(t, err, ErrInvalidTTL.Error(),
+			"expected err %s, got %s", ErrInvalidTTL, err)
+
+		err = cache.Persist("bulbasaur")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+}
+
+func TestSweep_TTL(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), time.Hour)
+	assert.NoError(t, err, "put should be successful with key pikachu")
+
+	t.Run("ttl should not count as an access", func(t *testing.T) {
+		ttl, err := cache.TTL("pikachu")
+		assert.NoError(t, err, "ttl should be successful")
+		assert.Truef(t, ttl > 0 && ttl <= time.Hour, "unexpected ttl %s", ttl)
+
+		hits := cache.Stats().Hits
+		assert.Equalf(t, uint64(0), hits, "expected %d hits, got %d", 0, hits)
+	})
+
+	t.Run("ttl of expired entry should fail", func(t *testing.T) {
+		err := cache.PutWithTTL("raichu", []byte("valueofraichu"), time.Millisecond)
+		assert.NoError(t, err, "put should be successful with key raichu")
+
+		time.Sleep(10 * time.Millisecond)
+
+		_, err = cache.TTL("raichu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+}
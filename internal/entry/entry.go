@@ -8,9 +8,42 @@ import (
 const (
 	frameLenLegth = 4 // bytes
 
+	flagsLength = 1 // bytes
+
 	timestampLength = 8 // bytes
 
+	expiryLength = 8 // bytes
+
 	hashedKeyLength = 8 // bytes
+
+	versionLength = 8 // bytes
+
+	keyLenLength = 4 // bytes
+
+	flagsOffset = frameLenLegth
+
+	timestampOffset = flagsOffset + flagsLength
+
+	expiryOffset = timestampOffset + timestampLength
+
+	hashedKeyOffset = expiryOffset + expiryLength
+
+	versionOffset = hashedKeyOffset + hashedKeyLength
+
+	keyLenOffset = versionOffset + versionLength
+
+	headerLength = keyLenOffset + keyLenLength
+)
+
+const (
+	// flagTombstone marks a frame whose entry has been deleted. The
+	// frame keeps occupying the queue until it reaches the front and
+	// gets popped.
+	flagTombstone byte = 1 << iota
+
+	// flagReferenced marks a frame whose entry has been read since the
+	// last time an eviction pass went over it.
+	flagReferenced
 )
 
 var (
@@ -19,6 +52,32 @@ var (
 	ErrEntryShortWrite = errors.New("short buffer to write from")
 )
 
+// Entry is the decoded form of a Frame.
+type Entry struct {
+	HashedKey uint64
+
+	// Timestamp is the unix time in nanoseconds the entry was put at.
+	Timestamp int64
+
+	// Expiry is the unix time in nanoseconds the entry expires at.
+	// A zero value means the entry never expires.
+	Expiry int64
+
+	// Version identifies the write which stored the entry. Every write
+	// of a key stores it with a different version.
+	Version uint64
+
+	Key []byte
+
+	Value []byte
+}
+
+// ExpiredAt reports whether the entry is expired at unix time now
+// in nanoseconds.
+func (e Entry) ExpiredAt(now int64) bool {
+	return isExpiredAt(e.Expiry, now)
+}
+
 // Frame is a binary representation of entry.
 type Frame []byte
 
@@ -26,59 +85,206 @@ func (f Frame) Len() int {
 	return len(f)
 }
 
-func ReadEntryIntoBuffer(hashedKey uint64, timestamp int64, val []byte, buf []byte) (int, error) {
-	frameLenNeeded := FrameLen(val)
+// IsTombstone reports whether the entry in the frame has been deleted.
+func (f Frame) IsTombstone() bool {
+	return f[flagsOffset]&flagTombstone != 0
+}
+
+// MarkTombstone marks the entry in the frame as deleted in place.
+func (f Frame) MarkTombstone() {
+	f[flagsOffset] |= flagTombstone
+}
+
+// IsReferenced reports whether the entry in the frame has been read
+// since its reference bit was last cleared.
+func (f Frame) IsReferenced() bool {
+	return f[flagsOffset]&flagReferenced != 0
+}
+
+// MarkReferenced sets the reference bit of the frame in place.
+func (f Frame) MarkReferenced() {
+	f[flagsOffset] |= flagReferenced
+}
+
+// ClearReferenced clears the reference bit of the frame in place.
+func (f Frame) ClearReferenced() {
+	f[flagsOffset] &^= flagReferenced
+}
+
+// SetExpiry overwrites the expiry of the entry in the frame in place.
+// A zero expiry means the entry never expires.
+func (f Frame) SetExpiry(expiry int64) {
+	binary.LittleEndian.PutUint64(f[expiryOffset:], uint64(expiry))
+}
+
+// SetVersion overwrites the version of the entry in the frame in place.
+func (f Frame) SetVersion(version uint64) {
+	binary.LittleEndian.PutUint64(f[versionOffset:], version)
+}
+
+func ReadEntryIntoBuffer(e Entry, buf []byte) (int, error) {
+	frameLenNeeded := FrameLen(e.Key, e.Value)
 
 	if frameLenNeeded > len(buf) {
 		return 0, ErrEntryShortBuffer
 	}
 
 	binary.LittleEndian.PutUint32(buf, uint32(frameLenNeeded))
-	binary.LittleEndian.PutUint64(buf[frameLenLegth:], uint64(timestamp))
-	binary.LittleEndian.PutUint64(buf[frameLenLegth+timestampLength:], hashedKey)
+	buf[flagsOffset] = 0
+	binary.LittleEndian.PutUint64(buf[timestampOffset:], uint64(e.Timestamp))
+	binary.LittleEndian.PutUint64(buf[expiryOffset:], uint64(e.Expiry))
+	binary.LittleEndian.PutUint64(buf[hashedKeyOffset:], e.HashedKey)
+	binary.LittleEndian.PutUint64(buf[versionOffset:], e.Version)
+	binary.LittleEndian.PutUint32(buf[keyLenOffset:], uint32(len(e.Key)))
 
-	copy(buf[frameLenLegth+timestampLength+hashedKeyLength:], val)
+	copy(buf[headerLength:], e.Key)
+	copy(buf[headerLength+len(e.Key):], e.Value)
 
 	return frameLenNeeded, nil
 }
 
-func GetEntryFromFrame(frame Frame) (hashedKey uint64, timestamp int64, val []byte, err error) {
-	frameLen := binary.LittleEndian.Uint32(frame)
-
-	if frameLen > uint32(len(frame)) {
-		err =  ErrEntryShortWrite
+func GetEntryFromFrame(frame Frame) (e Entry, err error) {
+	e, err = GetEntryWithoutKey(frame)
+	if err != nil {
 		return
 	}
 
-	timestamp = int64(binary.LittleEndian.Uint64(frame[frameLenLegth:]))
-
+	keyLen := binary.LittleEndian.Uint32(frame[keyLenOffset:])
+	e.Key = make([]byte, keyLen)
+	copy(e.Key, frame[headerLength:headerLength+keyLen])
+	return
+}
 
-	hashedKey = binary.LittleEndian.Uint64(frame[frameLenLegth+timestampLength:])
+// GetEntryWithoutKey returns the entry in the frame with a copy of its
+// value, leaving its key nil for callers which already hold it.
+func GetEntryWithoutKey(frame Frame) (e Entry, err error) {
+	frameLen, keyLen, err := checkFrame(frame)
+	if err != nil {
+		return
+	}
 
-	val = make([]byte, frameLen-(frameLenLegth+timestampLength+hashedKeyLength))
+	e.Timestamp = int64(binary.LittleEndian.Uint64(frame[timestampOffset:]))
+	e.Expiry = int64(binary.LittleEndian.Uint64(frame[expiryOffset:]))
+	e.HashedKey = binary.LittleEndian.Uint64(frame[hashedKeyOffset:])
+	e.Version = binary.LittleEndian.Uint64(frame[versionOffset:])
 
-	copy(val, frame[frameLenLegth+timestampLength+hashedKeyLength:frameLen])
+	e.Value = make([]byte, frameLen-headerLength-keyLen)
+	copy(e.Value, frame[headerLength+keyLen:frameLen])
 	return
 }
 
+// ValFromFrame returns a copy of the value of the entry in the frame,
+// without copying its key.
 func ValFromFrame(frame Frame) ([]byte, error) {
-	_, _, val, err := GetEntryFromFrame(frame)
+	frameLen, keyLen, err := checkFrame(frame)
 	if err != nil {
 		return nil, err
 	}
 
-	return val, err
+	val := make([]byte, frameLen-headerLength-keyLen)
+	copy(val, frame[headerLength+keyLen:frameLen])
+	return val, nil
+}
+
+func KeyFromFrame(frame Frame) ([]byte, error) {
+	e, err := GetEntryFromFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Key, err
+}
+
+// KeyView returns the key of the entry in the frame without copying it.
+// The returned slice aliases the frame.
+func KeyView(frame Frame) ([]byte, error) {
+	_, keyLen, err := checkFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	return frame[headerLength : headerLength+keyLen], nil
+}
+
+// ValView returns the value of the entry in the frame without copying
+// it. The returned slice aliases the frame.
+func ValView(frame Frame) ([]byte, error) {
+	frameLen, keyLen, err := checkFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	return frame[headerLength+keyLen : frameLen], nil
 }
 
 func TimestampFromFrame(frame Frame) (int64, error) {
-	_, tm, _, err := GetEntryFromFrame(frame)
+	if _, _, err := checkFrame(frame); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.LittleEndian.Uint64(frame[timestampOffset:])), nil
+}
+
+func ExpiryFromFrame(frame Frame) (int64, error) {
+	if _, _, err := checkFrame(frame); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.LittleEndian.Uint64(frame[expiryOffset:])), nil
+}
+
+// ExpiredAt reports whether the entry in the frame is expired at unix
+// time now in nanoseconds.
+func ExpiredAt(frame Frame, now int64) (bool, error) {
+	expiry, err := ExpiryFromFrame(frame)
 	if err != nil {
+		return false, err
+	}
+
+	return isExpiredAt(expiry, now), nil
+}
+
+func HashedKeyFromFrame(frame Frame) (uint64, error) {
+	if _, _, err := checkFrame(frame); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(frame[hashedKeyOffset:]), nil
+}
+
+func VersionFromFrame(frame Frame) (uint64, error) {
+	if _, _, err := checkFrame(frame); err != nil {
 		return 0, err
 	}
 
-	return tm, err
+	return binary.LittleEndian.Uint64(frame[versionOffset:]), nil
+}
+
+func FrameLen(key, val []byte) int {
+	return headerLength + len(key) + len(val)
+}
+
+func checkFrame(frame Frame) (frameLen, keyLen uint32, err error) {
+	if len(frame) < headerLength {
+		err = ErrEntryShortWrite
+		return
+	}
+
+	frameLen = binary.LittleEndian.Uint32(frame)
+	if frameLen > uint32(len(frame)) || frameLen < headerLength {
+		err = ErrEntryShortWrite
+		return
+	}
+
+	keyLen = binary.LittleEndian.Uint32(frame[keyLenOffset:])
+	if keyLen > frameLen-headerLength {
+		err = ErrEntryShortWrite
+		return
+	}
+
+	return
 }
 
-func FrameLen(val []byte) int {
-	return frameLenLegth + timestampLength + hashedKeyLength + len(val)
+func isExpiredAt(expiry, now int64) bool {
+	return expiry != 0 && expiry <= now
 }
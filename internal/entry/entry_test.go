@@ -6,54 +6,157 @@ import (
 )
 
 func TestEntry(t *testing.T) {
-	hardCodedFrame := []byte{27, 0, 0, 0, 161, 183, 175, 95, 0, 0, 0, 0, 78, 97, 188,
-		0, 0, 0, 0, 0, 112, 105, 107, 97, 99, 104, 117,}
+	hardCodedFrame := []byte{52, 0, 0, 0, 0, 161, 183, 175, 95, 0, 0, 0, 0, 221, 183, 175,
+		95, 0, 0, 0, 0, 78, 97, 188, 0, 0, 0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0,
+		112, 105, 107, 97, 112, 105, 107, 97, 99, 104, 117}
 
-	var hardCodedTimeStamp int64 = 1605351329
-	var hardCodedHashKey uint64 = 12345678
-	hardCodedVal := "pikachu"
+	hardCodedEntry := Entry{
+		HashedKey: 12345678,
+		Timestamp: 1605351329,
+		Expiry:    1605351389,
+		Version:   7,
+		Key:       []byte("pika"),
+		Value:     []byte("pikachu"),
+	}
 
 	t.Run("return non nil err when buff provided is short for Read", func(t *testing.T) {
 		buff := make([]byte, 1)
 
-		_, err := ReadEntryIntoBuffer(hardCodedHashKey, hardCodedTimeStamp, []byte(hardCodedVal), buff)
+		_, err := ReadEntryIntoBuffer(hardCodedEntry, buff)
 		assert.EqualError(t, err, ErrEntryShortBuffer.Error(), "err should be short buffer")
 	})
 
 	t.Run("return non nil err when buff provided is short for write", func(t *testing.T) {
 		buff := []byte{19, 0, 0, 0}
 
-		_, _ , _, err := GetEntryFromFrame(buff)
+		_, err := GetEntryFromFrame(buff)
 		assert.EqualError(t, err, ErrEntryShortWrite.Error(), "err should be short write")
 	})
 
 	t.Run("return nil err when buff provided is adequate for Read", func(t *testing.T) {
-		buff := make([]byte, FrameLen([]byte(hardCodedVal)))
+		buff := make([]byte, FrameLen(hardCodedEntry.Key, hardCodedEntry.Value))
 
-		_, err := ReadEntryIntoBuffer(hardCodedHashKey, hardCodedTimeStamp, []byte(hardCodedVal), buff)
+		_, err := ReadEntryIntoBuffer(hardCodedEntry, buff)
 		assert.NoError(t, err, "err should be nil")
 	})
 
 	t.Run("read valid frame into provided buff", func(t *testing.T) {
-		fl := FrameLen([]byte(hardCodedVal))
+		fl := FrameLen(hardCodedEntry.Key, hardCodedEntry.Value)
 		buff := make([]byte, fl)
 
-		n, err := ReadEntryIntoBuffer(hardCodedHashKey, hardCodedTimeStamp, []byte(hardCodedVal), buff)
+		n, err := ReadEntryIntoBuffer(hardCodedEntry, buff)
 		assert.NoError(t, err, "err should be nil")
 		assert.Equalf(t, fl, n, "expected %d, got %d", fl, n)
 		assert.Equal(t, hardCodedFrame, buff, "frame should match")
 	})
 
 	t.Run("write valid frame from provided buff", func(t *testing.T) {
-		hk, tm, val, err := GetEntryFromFrame(hardCodedFrame)
+		e, err := GetEntryFromFrame(hardCodedFrame)
 		assert.NoError(t, err, "err should be nil")
 
-		assert.Equalf(t, hardCodedVal, string(val), "expected `%s`, got `%s`",
-			hardCodedVal, val)
-		assert.Equalf(t, hardCodedTimeStamp, tm, "expected %d, got %d",
-			hardCodedTimeStamp, tm)
-		assert.Equalf(t, hardCodedHashKey, hk, "expected hashed key %d, got %d",
-			hardCodedHashKey, hk)
+		assert.Equal(t, hardCodedEntry, e, "entry should match")
 	})
 
+	t.Run("write valid frame without its key", func(t *testing.T) {
+		e, err := GetEntryWithoutKey(hardCodedFrame)
+		assert.NoError(t, err, "err should be nil")
+
+		expected := hardCodedEntry
+		expected.Key = nil
+		assert.Equal(t, expected, e, "entry should match without key")
+	})
+
+	t.Run("view key and value of frame without copying", func(t *testing.T) {
+		frame := make(Frame, len(hardCodedFrame))
+		copy(frame, hardCodedFrame)
+
+		key, err := KeyView(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equal(t, hardCodedEntry.Key, key, "key should match")
+
+		val, err := ValView(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equal(t, hardCodedEntry.Value, val, "value should match")
+
+		val[0] = 'P'
+		assert.Equal(t, byte('P'), frame[len(frame)-len(val)], "value should alias the frame")
+	})
+
+	t.Run("read expiry from frame", func(t *testing.T) {
+		expiry, err := ExpiryFromFrame(hardCodedFrame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equalf(t, hardCodedEntry.Expiry, expiry, "expected expiry %d, got %d",
+			hardCodedEntry.Expiry, expiry)
+
+		expired, err := ExpiredAt(hardCodedFrame, hardCodedEntry.Expiry-1)
+		assert.NoError(t, err, "err should be nil")
+		assert.False(t, expired, "entry should not be expired before its expiry")
+
+		expired, err = ExpiredAt(hardCodedFrame, hardCodedEntry.Expiry)
+		assert.NoError(t, err, "err should be nil")
+		assert.True(t, expired, "entry should be expired at its expiry")
+	})
+
+	t.Run("overwrite expiry of frame in place", func(t *testing.T) {
+		frame := make(Frame, len(hardCodedFrame))
+		copy(frame, hardCodedFrame)
+
+		frame.SetExpiry(hardCodedEntry.Expiry + 60)
+
+		expiry, err := ExpiryFromFrame(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equalf(t, hardCodedEntry.Expiry+60, expiry, "expected expiry %d, got %d",
+			hardCodedEntry.Expiry+60, expiry)
+	})
+
+	t.Run("overwrite version of frame in place", func(t *testing.T) {
+		frame := make(Frame, len(hardCodedFrame))
+		copy(frame, hardCodedFrame)
+
+		version, err := VersionFromFrame(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equalf(t, hardCodedEntry.Version, version, "expected version %d, got %d",
+			hardCodedEntry.Version, version)
+
+		frame.SetVersion(hardCodedEntry.Version + 1)
+
+		version, err = VersionFromFrame(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equalf(t, hardCodedEntry.Version+1, version, "expected version %d, got %d",
+			hardCodedEntry.Version+1, version)
+	})
+
+	t.Run("entry without expiry never expires", func(t *testing.T) {
+		e := Entry{Timestamp: hardCodedEntry.Timestamp}
+		assert.False(t, e.ExpiredAt(1<<62), "entry should never expire")
+	})
+
+	t.Run("set and clear reference bit of frame in place", func(t *testing.T) {
+		frame := make(Frame, len(hardCodedFrame))
+		copy(frame, hardCodedFrame)
+
+		assert.False(t, frame.IsReferenced(), "fresh frame should not be referenced")
+
+		frame.MarkReferenced()
+		assert.True(t, frame.IsReferenced(), "frame should be referenced")
+		assert.False(t, frame.IsTombstone(), "frame should not be a tombstone")
+
+		frame.ClearReferenced()
+		assert.False(t, frame.IsReferenced(), "frame should not be referenced")
+	})
+
+	t.Run("mark frame as tombstone in place", func(t *testing.T) {
+		frame := make(Frame, len(hardCodedFrame))
+		copy(frame, hardCodedFrame)
+
+		assert.False(t, frame.IsTombstone(), "fresh frame should not be a tombstone")
+
+		frame.MarkTombstone()
+		assert.True(t, frame.IsTombstone(), "frame should be a tombstone")
+
+		val, err := ValFromFrame(frame)
+		assert.NoError(t, err, "err should be nil")
+		assert.Equalf(t, hardCodedEntry.Value, val, "expected `%s`, got `%s`",
+			hardCodedEntry.Value, val)
+	})
 }
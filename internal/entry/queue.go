@@ -14,6 +14,8 @@ var (
 
 	ErrQueueMaxSizeReaced = errors.New("max queue size reached")
 
+	ErrQueueMinSizeReached = errors.New("min queue size reached")
+
 	ErrQueueEmpty = errors.New("queue is empty")
 )
 
@@ -30,15 +32,15 @@ func NewQueue(maxSize int) *Queue {
 }
 
 // Push attempt to return an index where the queue is pushed otherwise error.
-func (q *Queue) Push(hashedKey uint64, timestamp int64, val []byte) (int, error) {
-	frameSize := FrameLen(val)
+func (q *Queue) Push(e Entry) (int, error) {
+	frameSize := FrameLen(e.Key, e.Value)
 
 	idx, b := q.bipbuf.Reserve(frameSize)
 	if b == nil || len(b) < frameSize {
 		return 0, ErrQueueSpaceNotAvailable
 	}
 
-	k, err := ReadEntryIntoBuffer(hashedKey, timestamp, val, b)
+	k, err := ReadEntryIntoBuffer(e, b)
 	if err != nil {
 		// This should never happen
 		return 0, err
@@ -48,6 +50,22 @@ func (q *Queue) Push(hashedKey uint64, timestamp int64, val []byte) (int, error)
 	return idx, nil
 }
 
+// PushFrame attempt to push an already encoded frame at the back of
+// the queue and return the index where it is pushed otherwise error.
+func (q *Queue) PushFrame(frame Frame) (int, error) {
+	idx, b := q.bipbuf.Reserve(len(frame))
+	if b == nil || len(b) < len(frame) {
+		return 0, ErrQueueSpaceNotAvailable
+	}
+
+	// The frame may be a just popped one overlapping the reserved
+	// space, copy handles overlapping slices.
+	k := copy(b, frame)
+
+	q.bipbuf.Commit(k)
+	return idx, nil
+}
+
 // Pop attempt to return an entry frame from the front of the queue and returns it
 // otherwise error.
 func (q *Queue) Pop() (Frame, error) {
@@ -72,6 +90,13 @@ func (q *Queue) Front() (Frame, error) {
 	return b[:frameSize], nil
 }
 
+// FrontIndex returns the index of the frame at the front of the queue.
+// It is meaningful only when the queue isn't empty.
+func (q *Queue) FrontIndex() int {
+	idx, _ := q.bipbuf.RegionA()
+	return idx
+}
+
 // Peek attempt to return an entry frame at an index in the queue otherwise
 // error.
 func (q *Queue) PeekAt(idx int) (Frame, error) {
@@ -94,15 +119,98 @@ func (q *Queue) PeekAt(idx int) (Frame, error) {
 	return frame, nil
 }
 
+// Walk calls fn for every frame in the queue, from front to back,
+// together with the index the frame is stored at. Walking stops
+// early when fn returns false.
+func (q *Queue) Walk(fn func(idx int, frame Frame) bool) {
+	idxA, regionA := q.bipbuf.RegionA()
+	if !walkRegion(idxA, regionA, fn) {
+		return
+	}
+
+	idxB, regionB := q.bipbuf.RegionB()
+	walkRegion(idxB, regionB, fn)
+}
+
+// Remap translates indexes of frames in the queue from before a Grow
+// or Shrink to after it.
+type Remap struct {
+	idxRegionA, sizeOfRegionA, idxRegionB int
+}
+
+// Index returns the index after the resize of the frame which was at
+// idx before it.
+func (r Remap) Index(idx int) int {
+	// Resizing moves region A to the start of the new buffer, followed
+	// by region B.
+	if idx >= r.idxRegionA && idx < r.idxRegionA+r.sizeOfRegionA {
+		return idx - r.idxRegionA
+	}
+
+	return r.sizeOfRegionA + idx - r.idxRegionB
+}
+
 // Grow will increase the queue size to twice of current size with all data
-// intact. It throws error, if queue size reached it max limits.
-func (q *Queue) Grow() error {
+// intact. Frames move to other indexes, the returned Remap translates
+// their old indexes to new ones. It throws error, if queue size reached
+// it max limits.
+func (q *Queue) Grow() (Remap, error) {
 	if q.maxSize != 0 && (2*q.Capacity()) > q.maxSize {
-		return ErrQueueMaxSizeReaced
+		return Remap{}, ErrQueueMaxSizeReaced
 	}
 
+	remap := q.remap()
 	q.bipbuf.Grow()
-	return nil
+	return remap, nil
+}
+
+// Shrink will decrease the queue size to half of current size with all
+// data intact. Like Grow, it moves frames to other indexes and returns
+// a Remap translating their old indexes to new ones. It throws error,
+// if the queue is at its initial size already or its frames don't fit
+// in half of it.
+func (q *Queue) Shrink() (Remap, error) {
+	if q.Capacity()/2 < defaultEntryQueueSize {
+		return Remap{}, ErrQueueMinSizeReached
+	}
+
+	remap := q.remap()
+	if !q.bipbuf.Shrink() {
+		return Remap{}, ErrQueueSpaceNotAvailable
+	}
+
+	return remap, nil
+}
+
+// remap returns the Remap of resizing the queue in its current state.
+func (q *Queue) remap() Remap {
+	idxA, regionA := q.bipbuf.RegionA()
+	idxB, _ := q.bipbuf.RegionB()
+
+	return Remap{idxRegionA: idxA, sizeOfRegionA: len(regionA), idxRegionB: idxB}
+}
+
+// Compact rewrites the frames keep reports true for into a fresh buffer
+// of the same capacity, in queue order, and drops all the others. moved
+// is called with every rewritten frame and its new index.
+func (q *Queue) Compact(keep func(idx int, frame Frame) bool, moved func(newIdx int, frame Frame)) {
+	compacted := bipbuffer.New(uint64(q.Capacity()))
+
+	q.Walk(func(idx int, frame Frame) bool {
+		if !keep(idx, frame) {
+			return true
+		}
+
+		// Kept frames take no more space than all frames did, so they
+		// always fit.
+		newIdx, b := compacted.Reserve(len(frame))
+		compacted.Commit(copy(b, frame))
+
+		moved(newIdx, Frame(b))
+		return true
+	})
+
+	q.bipbuf = compacted
 }
 
 // Capacity returns the total capacity of the queue.
@@ -110,6 +218,11 @@ func (q *Queue) Capacity() int {
 	return q.bipbuf.Capacity()
 }
 
+// Size returns the number of bytes held by frames in the queue.
+func (q *Queue) Size() int {
+	return q.bipbuf.CommittedSize()
+}
+
 // SpaceAvailable returns true if queue has space for write equal to size.
 func (q *Queue) SpaceAvailable(size int) bool {
 	_, b := q.bipbuf.Reserve(size)
@@ -119,3 +232,22 @@ func (q *Queue) SpaceAvailable(size int) bool {
 
 	return true
 }
+
+func walkRegion(idx int, region []byte, fn func(idx int, frame Frame) bool) bool {
+	for len(region) >= frameLenLegth {
+		frameSize := int(binary.LittleEndian.Uint32(region))
+		if frameSize < frameLenLegth || frameSize > len(region) {
+			// This should never happen
+			return false
+		}
+
+		if !fn(idx, region[:frameSize]) {
+			return false
+		}
+
+		idx += frameSize
+		region = region[frameSize:]
+	}
+
+	return true
+}
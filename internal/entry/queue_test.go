@@ -8,7 +8,15 @@ import (
 var (
 	hardCodedTimeStamp int64  = 1605351329
 	hardCodedHashKey   uint64 = 12345678
+	hardCodedKey              = []byte("pika")
 	hardCodedVal              = []byte("pikachu")
+
+	hardCodedQueueEntry = Entry{
+		HashedKey: hardCodedHashKey,
+		Timestamp: hardCodedTimeStamp,
+		Key:       hardCodedKey,
+		Value:     hardCodedVal,
+	}
 )
 
 func TestQueue_Capacity(t *testing.T) {
@@ -28,39 +36,245 @@ func TestQueue_SpaceAvailable(t *testing.T) {
 
 func TestQueue_Push(t *testing.T) {
 	q := NewQueue(defaultEntryQueueSize)
-	idx, err := q.Push(hardCodedHashKey, hardCodedTimeStamp, hardCodedVal)
+	idx, err := q.Push(hardCodedQueueEntry)
 	assert.NoError(t, err, "push should be successful")
 
 	frame, err := q.PeekAt(idx)
 	assert.NoError(t, err, "peek should be successful")
 
-	_, tm, val, err := GetEntryFromFrame(frame)
+	e, err := GetEntryFromFrame(frame)
 	assert.NoError(t, err, "entry write should be successful")
 
-	assert.Equalf(t, hardCodedVal, val, "expected val %s, got %s",
-		hardCodedVal, val)
+	assert.Equalf(t, hardCodedKey, e.Key, "expected key %s, got %s",
+		hardCodedKey, e.Key)
+
+	assert.Equalf(t, hardCodedVal, e.Value, "expected val %s, got %s",
+		hardCodedVal, e.Value)
 
-	assert.Equalf(t, hardCodedTimeStamp, tm,
-		"expected timestamp %d, got %d", hardCodedTimeStamp, tm)
+	assert.Equalf(t, hardCodedTimeStamp, e.Timestamp,
+		"expected timestamp %d, got %d", hardCodedTimeStamp, e.Timestamp)
 }
 
 func TestQueue_Pop(t *testing.T) {
 	q := NewQueue(defaultEntryQueueSize)
-	_, err := q.Push(hardCodedHashKey, hardCodedTimeStamp, hardCodedVal)
+	_, err := q.Push(hardCodedQueueEntry)
+	assert.NoError(t, err, "push should be successful")
+
+	_, err = q.Push(Entry{
+		HashedKey: 9876543,
+		Timestamp: hardCodedTimeStamp + 9,
+		Key:       []byte("raichu"),
+		Value:     []byte("pikachu"),
+	})
 	assert.NoError(t, err, "push should be successful")
 
-	_, err = q.Push(9876543, hardCodedTimeStamp+9, []byte("pikachu"))
+	frame, err := q.Pop()
+	assert.NoError(t, err, "pop should be successful")
+
+	e, err := GetEntryFromFrame(frame)
+	assert.NoError(t, err, "entry write should be successful")
+
+	assert.Equalf(t, hardCodedKey, e.Key, "expected key %s, got %s",
+		hardCodedKey, e.Key)
+
+	assert.Equalf(t, hardCodedVal, e.Value, "expected val %v, got %v",
+		hardCodedVal, e.Value)
+
+	assert.Equalf(t, hardCodedTimeStamp, e.Timestamp,
+		"expected timestamp %d, got %d", hardCodedTimeStamp, e.Timestamp)
+}
+
+func TestQueue_PushFrame(t *testing.T) {
+	q := NewQueue(defaultEntryQueueSize)
+	_, err := q.Push(hardCodedQueueEntry)
 	assert.NoError(t, err, "push should be successful")
 
 	frame, err := q.Pop()
 	assert.NoError(t, err, "pop should be successful")
 
-	_, tm, val, err := GetEntryFromFrame(frame)
+	idx, err := q.PushFrame(frame)
+	assert.NoError(t, err, "push frame should be successful")
+	assert.Equalf(t, idx, q.FrontIndex(), "expected front index %d, got %d",
+		idx, q.FrontIndex())
+
+	pushedFrame, err := q.PeekAt(idx)
+	assert.NoError(t, err, "peek should be successful")
+
+	e, err := GetEntryFromFrame(pushedFrame)
 	assert.NoError(t, err, "entry write should be successful")
+	assert.Equal(t, hardCodedQueueEntry, e, "entry should match")
+}
+
+func TestQueue_Walk(t *testing.T) {
+	q := NewQueue(defaultEntryQueueSize)
+
+	var pushed []int
+	for i := 0; i < 3; i++ {
+		e := hardCodedQueueEntry
+		e.HashedKey = uint64(i)
+
+		idx, err := q.Push(e)
+		assert.NoError(t, err, "push should be successful")
+		pushed = append(pushed, idx)
+	}
+
+	var walked []int
+	q.Walk(func(idx int, frame Frame) bool {
+		hk, err := HashedKeyFromFrame(frame)
+		assert.NoError(t, err, "frame should be valid")
+		assert.Equalf(t, uint64(len(walked)), hk, "expected hashed key %d, got %d",
+			len(walked), hk)
+
+		walked = append(walked, idx)
+		return true
+	})
+
+	assert.Equal(t, pushed, walked, "walk should visit frames in push order")
+}
+
+func TestQueue_Grow(t *testing.T) {
+	q := NewQueue(0)
+
+	// Fill the queue, then pop from the front and push again so frames
+	// wrap around into region B before growing.
+	indexes := make(map[uint64]int)
+	var hk uint64
+	for ; q.SpaceAvailable(FrameLen(hardCodedKey, hardCodedVal)); hk++ {
+		e := hardCodedQueueEntry
+		e.HashedKey = hk
+
+		idx, err := q.Push(e)
+		assert.NoError(t, err, "push should be successful")
+		indexes[hk] = idx
+	}
+
+	for i := uint64(0); i < hk/2; i++ {
+		_, err := q.Pop()
+		assert.NoError(t, err, "pop should be successful")
+		delete(indexes, i)
+	}
+
+	for ; q.SpaceAvailable(FrameLen(hardCodedKey, hardCodedVal)); hk++ {
+		e := hardCodedQueueEntry
+		e.HashedKey = hk
+
+		idx, err := q.Push(e)
+		assert.NoError(t, err, "push should be successful")
+		indexes[hk] = idx
+	}
+
+	wrapped := false
+	for _, idx := range indexes {
+		wrapped = wrapped || idx < q.FrontIndex()
+	}
+	assert.True(t, wrapped, "frames should wrap around before growing")
+
+	capacity := q.Capacity()
+	remap, err := q.Grow()
+	assert.NoError(t, err, "grow should be successful")
+	assert.Equalf(t, 2*capacity, q.Capacity(), "expected capacity %d, got %d",
+		2*capacity, q.Capacity())
+
+	for want, idx := range indexes {
+		frame, err := q.PeekAt(remap.Index(idx))
+		assert.NoError(t, err, "peek should be successful")
+
+		got, err := HashedKeyFromFrame(frame)
+		assert.NoError(t, err, "frame should be valid")
+		assert.Equalf(t, want, got, "expected hashed key %d, got %d", want, got)
+	}
+}
+
+func TestQueue_Compact(t *testing.T) {
+	q := NewQueue(defaultEntryQueueSize)
+
+	indexes := make(map[uint64]int)
+	for i := uint64(0); i < 4; i++ {
+		e := hardCodedQueueEntry
+		e.HashedKey = i
+
+		idx, err := q.Push(e)
+		assert.NoError(t, err, "push should be successful")
+		indexes[i] = idx
+	}
+
+	capacity := q.Capacity()
+	size := q.Size()
+
+	moved := make(map[uint64]int)
+	q.Compact(func(idx int, frame Frame) bool {
+		hk, err := HashedKeyFromFrame(frame)
+		assert.NoError(t, err, "frame should be valid")
+		assert.Equalf(t, indexes[hk], idx, "expected index %d, got %d", indexes[hk], idx)
+
+		return hk%2 == 1
+	}, func(newIdx int, frame Frame) {
+		hk, err := HashedKeyFromFrame(frame)
+		assert.NoError(t, err, "frame should be valid")
+		moved[hk] = newIdx
+	})
+
+	assert.Equalf(t, capacity, q.Capacity(), "expected capacity %d, got %d", capacity, q.Capacity())
+	assert.Equalf(t, size/2, q.Size(), "expected size %d, got %d", size/2, q.Size())
+	assert.Lenf(t, moved, 2, "expected %d moved frames, got %d", 2, len(moved))
+
+	for want, idx := range moved {
+		frame, err := q.PeekAt(idx)
+		assert.NoError(t, err, "peek should be successful")
+
+		got, err := HashedKeyFromFrame(frame)
+		assert.NoError(t, err, "frame should be valid")
+		assert.Equalf(t, want, got, "expected hashed key %d, got %d", want, got)
+	}
+}
+
+func TestQueue_Shrink(t *testing.T) {
+	q := NewQueue(0)
+
+	t.Run("shrink should fail at initial size", func(t *testing.T) {
+		_, err := q.Shrink()
+		assert.EqualError(t, err, ErrQueueMinSizeReached.Error(), "err should be min size reached")
+	})
+
+	_, err := q.Grow()
+	assert.NoError(t, err, "grow should be successful")
+
+	t.Run("shrink should fail if frames don't fit", func(t *testing.T) {
+		for q.Size() <= q.Capacity()/2 {
+			_, err := q.Push(hardCodedQueueEntry)
+			assert.NoError(t, err, "push should be successful")
+		}
+
+		_, err := q.Shrink()
+		assert.EqualError(t, err, ErrQueueSpaceNotAvailable.Error(), "err should be space not available")
+	})
+
+	t.Run("shrink should keep frames", func(t *testing.T) {
+		for q.Size() > q.Capacity()/4 {
+			_, err := q.Pop()
+			assert.NoError(t, err, "pop should be successful")
+		}
+
+		indexes := make(map[uint64]int)
+		q.Walk(func(idx int, frame Frame) bool {
+			frame.SetVersion(uint64(len(indexes)))
+			indexes[uint64(len(indexes))] = idx
+			return true
+		})
+
+		capacity := q.Capacity()
+		remap, err := q.Shrink()
+		assert.NoError(t, err, "shrink should be successful")
+		assert.Equalf(t, capacity/2, q.Capacity(), "expected capacity %d, got %d",
+			capacity/2, q.Capacity())
 
-	assert.Equalf(t, hardCodedVal, val, "expected val %v, got %v",
-		hardCodedVal, val)
+		for want, idx := range indexes {
+			frame, err := q.PeekAt(remap.Index(idx))
+			assert.NoError(t, err, "peek should be successful")
 
-	assert.Equalf(t, hardCodedTimeStamp, tm,
-		"expected timestamp %d, got %d", hardCodedTimeStamp, tm)
+			got, err := VersionFromFrame(frame)
+			assert.NoError(t, err, "frame should be valid")
+			assert.Equalf(t, want, got, "expected version %d, got %d", want, got)
+		}
+	})
 }
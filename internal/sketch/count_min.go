@@ -0,0 +1,102 @@
+package sketch
+
+import "sync"
+
+const depth = 4
+
+// seeds are odd constants used to derive an independent counter index
+// per row from a single 64-bit hash.
+var seeds = [depth]uint64{
+	0xc3a5c85c97cb3127,
+	0xb492b66fbe98f273,
+	0x9ae16a3b2f90404f,
+	0xcbf29ce484222325,
+}
+
+// CountMin is a count-min sketch estimating how often a hash has been
+// seen. Counters saturate at 255 and all of them are halved once the
+// number of increments reaches ten times the width of the sketch, so
+// estimates reflect recent frequency rather than all time frequency.
+// It is safe for concurrent use.
+type CountMin struct {
+	rows [depth][]uint8
+
+	mask uint64
+
+	increments int
+	resetAt    int
+
+	mu sync.Mutex
+}
+
+// New returns a count-min sketch with rows of width counters. Width
+// should be power of two. If it is not, then it will be set to next
+// power of two greater than current value.
+func New(width int) *CountMin {
+	w := 1
+	for w < width {
+		w = w << 1
+	}
+
+	c := &CountMin{
+		mask:    uint64(w - 1),
+		resetAt: 10 * w,
+	}
+
+	for i := range c.rows {
+		c.rows[i] = make([]uint8, w)
+	}
+
+	return c
+}
+
+// Increment records one occurrence of the hash.
+func (c *CountMin) Increment(hash uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.rows {
+		idx := c.index(hash, i)
+		if c.rows[i][idx] < 255 {
+			c.rows[i][idx]++
+		}
+	}
+
+	c.increments++
+	if c.increments >= c.resetAt {
+		c.age()
+	}
+}
+
+// Estimate returns the estimated number of occurrences of the hash.
+// It never underestimates, apart from the effect of aging.
+func (c *CountMin) Estimate(hash uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	min := uint8(255)
+	for i := range c.rows {
+		if v := c.rows[i][c.index(hash, i)]; v < min {
+			min = v
+		}
+	}
+
+	return int(min)
+}
+
+func (c *CountMin) index(hash uint64, row int) uint64 {
+	h := hash * seeds[row]
+	h ^= h >> 32
+	return h & c.mask
+}
+
+// age halves every counter.
+func (c *CountMin) age() {
+	for i := range c.rows {
+		for j := range c.rows[i] {
+			c.rows[i][j] >>= 1
+		}
+	}
+
+	c.increments /= 2
+}
@@ -0,0 +1,41 @@
+package sketch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCountMin_Estimate(t *testing.T) {
+	c := New(64)
+
+	for i := 0; i < 5; i++ {
+		c.Increment(42)
+	}
+	c.Increment(7)
+
+	assert.GreaterOrEqualf(t, c.Estimate(42), 5,
+		"expected estimate at least %d, got %d", 5, c.Estimate(42))
+	assert.GreaterOrEqualf(t, c.Estimate(7), 1,
+		"expected estimate at least %d, got %d", 1, c.Estimate(7))
+	assert.Lessf(t, c.Estimate(7), c.Estimate(42),
+		"expected estimate of rare hash %d to be less than frequent hash %d",
+		c.Estimate(7), c.Estimate(42))
+}
+
+func TestCountMin_Aging(t *testing.T) {
+	c := New(64)
+
+	for i := 0; i < 100; i++ {
+		c.Increment(42)
+	}
+	before := c.Estimate(42)
+
+	// Push the sketch past its reset point with other hashes.
+	for i := 0; i < 10*64; i++ {
+		c.Increment(uint64(1000 + i))
+	}
+
+	assert.Lessf(t, c.Estimate(42), before,
+		"expected estimate to decrease after aging, before %d, after %d",
+		before, c.Estimate(42))
+}
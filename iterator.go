@@ -0,0 +1,53 @@
+package sweep
+
+import "time"
+
+// Item is a key and its value stored in the sweep.
+type Item struct {
+	Key string
+
+	Value []byte
+}
+
+// Iterator walks over live entries of the sweep. It visits one shard
+// at a time, copying the shard's live entries while holding its read
+// lock, so writers are only held off for a single shard at a time.
+// Changes made to a shard after it was visited are not seen.
+type Iterator struct {
+	s *Sweep
+
+	shardIdx int
+
+	items []Item
+
+	current Item
+}
+
+// Iterator returns an iterator over live entries of the sweep, skipping
+// entries which are deleted, overwritten or expired.
+func (s *Sweep) Iterator() *Iterator {
+	return &Iterator{s: s}
+}
+
+// Next advances the iterator to the next entry and reports whether
+// there is one. It returns false once all shards are visited or the
+// sweep is closed.
+func (it *Iterator) Next() bool {
+	for len(it.items) == 0 {
+		if it.s.isClosed() || it.shardIdx >= len(it.s.shards) {
+			return false
+		}
+
+		it.items = it.s.shards[it.shardIdx].liveItems(time.Now().UnixNano())
+		it.shardIdx++
+	}
+
+	it.current = it.items[0]
+	it.items = it.items[1:]
+	return true
+}
+
+// Value returns the entry the iterator is at.
+func (it *Iterator) Value() Item {
+	return it.current
+}
@@ -0,0 +1,209 @@
+package sweep
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader loads values of keys missing in the sweep.
+type Loader interface {
+	// Load returns the value of the key from the source of truth.
+	Load(ctx context.Context, key string) ([]byte, error)
+}
+
+// GetOrLoad retrieves value associated with the key from the sweep.
+// On a miss the value is loaded with the configured Loader and put
+// in the sweep. Concurrent misses for the same key share a single
+// load. The loaded value is returned even if it couldn't be put.
+//
+// An entry expired less than StaleGracePeriod ago is stale. A stale
+// value is returned right away while it is reloaded in the background,
+// and it is returned in place of the error if loading fails.
+func (s *Sweep) GetOrLoad(ctx context.Context, key string) ([]byte, error) {
+	if s.cfg.Loader == nil {
+		return nil, ErrNoLoader
+	}
+
+	if s.isClosed() {
+		return nil, ErrClosed
+	}
+
+	now := time.Now().UnixNano()
+
+	e, err := s.getEntry(key, now-int64(s.cfg.StaleGracePeriod))
+	if err != nil && err != ErrEntryNotFound {
+		return nil, err
+	}
+
+	found := err == nil
+	stale := found && e.ExpiredAt(now)
+
+	if found && !stale {
+		s.maybeRefresh(key, e.Timestamp, e.Expiry, now)
+		return e.Value, nil
+	}
+
+	if stale && s.refresh != nil {
+		// Stale while revalidate
+		s.refresh.schedule(refreshTask{key: key, lifetime: time.Duration(e.Expiry - e.Timestamp)})
+		return e.Value, nil
+	}
+
+	val, err := s.load(ctx, key, now)
+	if err != nil && stale {
+		// Stale if error
+		return e.Value, nil
+	}
+
+	return val, err
+}
+
+// load loads the value of the key with the configured Loader and
+// puts it in the sweep, unless loading the key failed recently.
+func (s *Sweep) load(ctx context.Context, key string, now int64) ([]byte, error) {
+	if err := s.loadErrs.get(key, now); err != nil {
+		return nil, err
+	}
+
+	return s.loads.do(ctx, key, func() ([]byte, error) {
+		val, err := s.cfg.Loader.Load(ctx, key)
+		if err != nil {
+			// A load ended by the caller's context says nothing
+			// about the backend.
+			if s.cfg.LoaderErrorTTL > 0 && ctx.Err() == nil {
+				s.loadErrs.put(key, err, unixExpiry(time.Now().Add(s.cfg.LoaderErrorTTL)))
+			}
+
+			return nil, err
+		}
+
+		_ = s.Put(key, val)
+		return val, nil
+	})
+}
+
+// loadCall is an in-flight or completed load of a key.
+type loadCall struct {
+	done chan struct{}
+
+	val []byte
+	err error
+}
+
+// loadGroup deduplicates concurrent loads of the same key.
+type loadGroup struct {
+	mu sync.Mutex
+
+	calls map[string]*loadCall
+}
+
+// do runs fn for the key unless a load of the key is already in
+// flight, in which case it waits for that load and returns its
+// result. Waiting stops early when ctx is done. fn runs with the
+// context of the caller starting the load, so a waiter whose own ctx
+// is still live starts another load if that context ended the load.
+func (g *loadGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	for {
+		g.mu.Lock()
+		if g.calls == nil {
+			g.calls = make(map[string]*loadCall)
+		}
+
+		c, ok := g.calls[key]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-c.done:
+			if isContextErr(c.err) && ctx.Err() == nil {
+				continue
+			}
+
+			return c.val, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c := &loadCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Waiters must be released and the key freed for later loads even
+	// if fn panics.
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	// Overwritten by the result of fn, unless it panics.
+	c.err = ErrLoaderPanicked
+	c.val, c.err = fn()
+
+	return c.val, c.err
+}
+
+func isContextErr(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+type loadErr struct {
+	err error
+
+	// expiry is the unix time in nanoseconds the error expires at.
+	expiry int64
+}
+
+// loadErrCache remembers failed loads for a while, so a failing
+// backend isn't hit on every miss.
+type loadErrCache struct {
+	mu sync.Mutex
+
+	errs map[string]loadErr
+}
+
+func (c *loadErrCache) get(key string, now int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	le, ok := c.errs[key]
+	if !ok {
+		return nil
+	}
+
+	if le.expiry <= now {
+		delete(c.errs, key)
+		return nil
+	}
+
+	return le.err
+}
+
+func (c *loadErrCache) put(key string, err error, expiry int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.errs == nil {
+		c.errs = make(map[string]loadErr)
+	}
+
+	c.errs[key] = loadErr{err: err, expiry: expiry}
+}
+
+// cleanup removes errors expired at unix time now in nanoseconds.
+func (c *loadErrCache) cleanup(now int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, le := range c.errs {
+		if le.expiry <= now {
+			delete(c.errs, key)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package sweep
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLoader is a Loader counting its loads, which blocks every load
+// until release is closed or its context is done, if release is set.
+type testLoader struct {
+	loads int32
+
+	release chan struct{}
+
+	err error
+
+	panics bool
+}
+
+func (l *testLoader) Load(ctx context.Context, key string) ([]byte, error) {
+	atomic.AddInt32(&l.loads, 1)
+
+	if l.panics {
+		panic("load failed")
+	}
+
+	if l.release != nil {
+		select {
+		case <-l.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return []byte("loadedvalueof" + key), nil
+}
+
+func (l *testLoader) loadsCount() int {
+	return int(atomic.LoadInt32(&l.loads))
+}
+
+func TestSweep_GetOrLoad(t *testing.T) {
+	t.Run("missing key should be loaded and put", func(t *testing.T) {
+		loader := &testLoader{}
+		cache := New(Configuration{Loader: loader})
+		defer cache.Close()
+
+		val, err := cache.GetOrLoad(context.Background(), "pikachu")
+		assert.NoError(t, err, "get or load should be successful")
+		assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+			"loadedvalueofpikachu", val)
+
+		val, err = cache.Get("pikachu")
+		assert.NoError(t, err, "loaded value should be put")
+		assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+			"loadedvalueofpikachu", val)
+
+		_, err = cache.GetOrLoad(context.Background(), "pikachu")
+		assert.NoError(t, err, "get or load should be successful")
+		assert.Equalf(t, 1, loader.loadsCount(), "expected loads %d, got %d",
+			1, loader.loadsCount())
+	})
+
+	t.Run("concurrent misses should share a single load", func(t *testing.T) {
+		loader := &testLoader{release: make(chan struct{})}
+		cache := New(Configuration{Loader: loader})
+		defer cache.Close()
+
+		callers := 10
+
+		var wg sync.WaitGroup
+		wg.Add(callers)
+		for i := 0; i < callers; i++ {
+			go func() {
+				defer wg.Done()
+
+				val, err := cache.GetOrLoad(context.Background(), "pikachu")
+				assert.NoError(t, err, "get or load should be successful")
+				assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+					"loadedvalueofpikachu", val)
+			}()
+		}
+
+		// Give every caller the chance to miss before the load ends.
+		time.Sleep(50 * time.Millisecond)
+		close(loader.release)
+		wg.Wait()
+
+		assert.Equalf(t, 1, loader.loadsCount(), "expected loads %d, got %d",
+			1, loader.loadsCount())
+	})
+
+	t.Run("loader errors should be cached when configured", func(t *testing.T) {
+		loadErr := errors.New("backend down")
+		loader := &testLoader{err: loadErr}
+		cache := New(Configuration{Loader: loader, LoaderErrorTTL: time.Hour})
+		defer cache.Close()
+
+		for i := 0; i < 3; i++ {
+			_, err := cache.GetOrLoad(context.Background(), "pikachu")
+			assert.EqualErrorf(t, err, loadErr.Error(), "expected err %s, got %s", loadErr, err)
+		}
+
+		assert.Equalf(t, 1, loader.loadsCount(), "expected loads %d, got %d",
+			1, loader.loadsCount())
+	})
+
+	t.Run("loader errors should not be cached by default", func(t *testing.T) {
+		loadErr := errors.New("backend down")
+		loader := &testLoader{err: loadErr}
+		cache := New(Configuration{Loader: loader})
+		defer cache.Close()
+
+		for i := 0; i < 3; i++ {
+			_, err := cache.GetOrLoad(context.Background(), "pikachu")
+			assert.EqualErrorf(t, err, loadErr.Error(), "expected err %s, got %s", loadErr, err)
+		}
+
+		assert.Equalf(t, 3, loader.loadsCount(), "expected loads %d, got %d",
+			3, loader.loadsCount())
+	})
+
+	t.Run("stale value should be served while reloading", func(t *testing.T) {
+		loader := &testLoader{release: make(chan struct{})}
+		cache := New(Configuration{
+			Loader:            loader,
+			StaleGracePeriod:  time.Hour,
+			RefreshAheadRatio: 0.5,
+		})
+		defer cache.Close()
+
+		// The reloaded entry gets the same lifetime.
+		lifetime := 200 * time.Millisecond
+		err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), lifetime)
+		assert.NoError(t, err, "put should be successful")
+		time.Sleep(lifetime + 10*time.Millisecond)
+
+		val, err := cache.GetOrLoad(context.Background(), "pikachu")
+		assert.NoError(t, err, "get or load should be successful")
+		assert.Equalf(t, []byte("valueofpikachu"), val, "expected stale %s, got %s",
+			"valueofpikachu", val)
+
+		close(loader.release)
+		time.Sleep(50 * time.Millisecond)
+
+		val, err = cache.Get("pikachu")
+		assert.NoError(t, err, "reloaded value should be put")
+		assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+			"loadedvalueofpikachu", val)
+	})
+
+	t.Run("stale value should be served when loading fails", func(t *testing.T) {
+		loader := &testLoader{err: errors.New("backend down")}
+		cache := New(Configuration{Loader: loader, StaleGracePeriod: time.Hour})
+		defer cache.Close()
+
+		err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), time.Millisecond)
+		assert.NoError(t, err, "put should be successful")
+		time.Sleep(10 * time.Millisecond)
+
+		val, err := cache.GetOrLoad(context.Background(), "pikachu")
+		assert.NoError(t, err, "get or load should fall back to stale value")
+		assert.Equalf(t, []byte("valueofpikachu"), val, "expected stale %s, got %s",
+			"valueofpikachu", val)
+		assert.Equalf(t, 1, loader.loadsCount(), "expected loads %d, got %d",
+			1, loader.loadsCount())
+	})
+
+	t.Run("panicking load should not block later loads", func(t *testing.T) {
+		loader := &testLoader{panics: true}
+		cache := New(Configuration{Loader: loader})
+		defer cache.Close()
+
+		func() {
+			defer func() {
+				assert.NotNil(t, recover(), "get or load should panic")
+			}()
+
+			_, _ = cache.GetOrLoad(context.Background(), "pikachu")
+		}()
+
+		loader.panics = false
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		val, err := cache.GetOrLoad(ctx, "pikachu")
+		assert.NoError(t, err, "get or load should be successful")
+		assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+			"loadedvalueofpikachu", val)
+		assert.Equalf(t, 2, loader.loadsCount(), "expected loads %d, got %d",
+			2, loader.loadsCount())
+	})
+
+	t.Run("cancelled leader should not fail live waiters", func(t *testing.T) {
+		loader := &testLoader{release: make(chan struct{})}
+		cache := New(Configuration{Loader: loader, LoaderErrorTTL: time.Hour})
+		defer cache.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		leaderDone := make(chan struct{})
+		go func() {
+			defer close(leaderDone)
+
+			_, err := cache.GetOrLoad(ctx, "pikachu")
+			assert.EqualErrorf(t, err, context.Canceled.Error(), "expected err %s, got %s",
+				context.Canceled, err)
+		}()
+
+		// Let the leader start the load before the waiter joins it.
+		time.Sleep(50 * time.Millisecond)
+
+		waiterDone := make(chan struct{})
+		go func() {
+			defer close(waiterDone)
+
+			val, err := cache.GetOrLoad(context.Background(), "pikachu")
+			assert.NoError(t, err, "get or load should be successful")
+			assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+				"loadedvalueofpikachu", val)
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		<-leaderDone
+
+		close(loader.release)
+		<-waiterDone
+
+		assert.Equalf(t, 2, loader.loadsCount(), "expected loads %d, got %d",
+			2, loader.loadsCount())
+	})
+
+	t.Run("get or load without loader should fail", func(t *testing.T) {
+		cache := Default()
+		defer cache.Close()
+
+		_, err := cache.GetOrLoad(context.Background(), "pikachu")
+		assert.EqualErrorf(t, err, ErrNoLoader.Error(), "expected err %s, got %s",
+			ErrNoLoader, err)
+	})
+}
@@ -0,0 +1,107 @@
+package sweep
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// refreshTask is a key to be reloaded ahead of its expiry.
+type refreshTask struct {
+	key string
+
+	// lifetime is the lifetime the refreshed entry is put with.
+	lifetime time.Duration
+}
+
+// refresher reloads entries nearing expiry, or stale ones, in the
+// background with a fixed pool of workers. Tasks which don't fit in its queue are
+// dropped, the entry simply expires then.
+type refresher struct {
+	tasks chan refreshTask
+
+	mu sync.Mutex
+
+	// pending holds keys queued or being refreshed, so a hot key is
+	// refreshed once no matter how many reads hit it.
+	pending map[string]struct{}
+}
+
+func (s *Sweep) startRefreshWorkers() {
+	r := &refresher{
+		tasks:   make(chan refreshTask, refreshQueueSizePerWorker*s.cfg.RefreshWorkers),
+		pending: make(map[string]struct{}),
+	}
+	s.refresh = r
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.closeCh
+		cancel()
+	}()
+
+	for i := 0; i < s.cfg.RefreshWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-s.closeCh:
+					return
+				case task := <-r.tasks:
+					s.refreshEntry(ctx, task)
+					r.done(task.key)
+				}
+			}
+		}()
+	}
+}
+
+// maybeRefresh schedules a refresh of the key if its entry, put at
+// timestamp and expiring at expiry, has lived past the configured
+// fraction of its lifetime by now. Times are unix nanoseconds.
+func (s *Sweep) maybeRefresh(key string, timestamp, expiry, now int64) {
+	if s.refresh == nil || expiry == 0 {
+		return
+	}
+
+	lifetime := expiry - timestamp
+	if float64(now-timestamp) < s.cfg.RefreshAheadRatio*float64(lifetime) {
+		return
+	}
+
+	s.refresh.schedule(refreshTask{key: key, lifetime: time.Duration(lifetime)})
+}
+
+func (s *Sweep) refreshEntry(ctx context.Context, task refreshTask) {
+	_, _ = s.loads.do(ctx, task.key, func() ([]byte, error) {
+		val, err := s.cfg.Loader.Load(ctx, task.key)
+		if err != nil {
+			// Keep serving the current value until it expires.
+			return nil, err
+		}
+
+		_ = s.PutWithTTL(task.key, val, task.lifetime)
+		return val, nil
+	})
+}
+
+func (r *refresher) schedule(task refreshTask) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pending[task.key]; ok {
+		return
+	}
+
+	select {
+	case r.tasks <- task:
+		r.pending[task.key] = struct{}{}
+	default:
+	}
+}
+
+func (r *refresher) done(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, key)
+}
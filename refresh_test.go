@@ -0,0 +1,56 @@
+package sweep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweepRefreshAhead(t *testing.T) {
+	loader := &testLoader{}
+	cache := New(Configuration{
+		ShardsCount:       1,
+		CleanupInterval:   time.Hour,
+		Loader:            loader,
+		RefreshAheadRatio: 0.5,
+	})
+	defer cache.Close()
+
+	lifetime := 300 * time.Millisecond
+	err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), lifetime)
+	assert.NoError(t, err, "put should be successful")
+
+	t.Run("young entry should not be refreshed", func(t *testing.T) {
+		_, err := cache.Get("pikachu")
+		assert.NoError(t, err, "get should be successful")
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equalf(t, 0, loader.loadsCount(), "expected loads %d, got %d",
+			0, loader.loadsCount())
+	})
+
+	t.Run("entry nearing expiry should be refreshed", func(t *testing.T) {
+		time.Sleep(lifetime / 2)
+
+		val, err := cache.Get("pikachu")
+		assert.NoError(t, err, "get should be successful")
+		assert.Equalf(t, []byte("valueofpikachu"), val,
+			"stale value should be served until refreshed, got %s", val)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equalf(t, 1, loader.loadsCount(), "expected loads %d, got %d",
+			1, loader.loadsCount())
+
+		val, err = cache.Get("pikachu")
+		assert.NoError(t, err, "get should be successful")
+		assert.Equalf(t, []byte("loadedvalueofpikachu"), val, "expected %s, got %s",
+			"loadedvalueofpikachu", val)
+
+		// The refreshed entry got a fresh lifetime.
+		time.Sleep(lifetime / 2)
+
+		_, err = cache.Get("pikachu")
+		assert.NoError(t, err, "refreshed entry should outlive the original expiry")
+	})
+}
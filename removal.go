@@ -0,0 +1,94 @@
+package sweep
+
+import "github.com/ataul443/sweep/internal/entry"
+
+// RemoveReason tells why an entry left the sweep.
+type RemoveReason int
+
+const (
+	// Expired means the entry outlived its lifetime and was removed
+	// by cleanup.
+	Expired RemoveReason = iota
+
+	// Evicted means the entry was evicted to make room for another,
+	// or displaced by a key with the same hash.
+	Evicted
+
+	// Deleted means the entry was removed by Delete.
+	Deleted
+
+	// Replaced means the entry was overwritten by a newer value.
+	Replaced
+)
+
+func (r RemoveReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Evicted:
+		return "evicted"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// removal is an entry which left a shard, waiting to be reported to
+// the OnRemove callback.
+type removal struct {
+	key string
+
+	value []byte
+
+	reason RemoveReason
+}
+
+// recordRemoval queues the entry in the frame for the OnRemove callback.
+// It must be called under the write lock, before the frame is
+// overwritten.
+func (sh *shard) recordRemoval(frame entry.Frame, reason RemoveReason) {
+	if sh.onRemove == nil {
+		return
+	}
+
+	e, err := entry.GetEntryFromFrame(frame)
+	if err != nil {
+		return
+	}
+
+	sh.removals = append(sh.removals, removal{
+		key:    string(e.Key),
+		value:  e.Value,
+		reason: reason,
+	})
+}
+
+// takeRemovals returns queued removals and resets the queue. It must be
+// called under the write lock.
+func (sh *shard) takeRemovals() []removal {
+	removed := sh.removals
+	sh.removals = nil
+	return removed
+}
+
+// notifyRemovals calls the OnRemove callback for every removal. It must
+// be called without holding the shard lock, so a slow callback doesn't
+// block the shard.
+func (sh *shard) notifyRemovals(removed []removal) {
+	for _, r := range removed {
+		sh.onRemove(r.key, r.value, r.reason)
+	}
+}
+
+// unlockAndNotify releases the write lock and reports removals recorded
+// under it. Deferring it keeps the shard usable if a caller supplied
+// function running under the lock panics.
+func (sh *shard) unlockAndNotify() {
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
+
+	sh.notifyRemovals(removed)
+}
@@ -1,108 +1,834 @@
 package sweep
 
 import (
-	"github.com/ataul443/sweep/internal/entry"
+	"bytes"
 	"sync"
-	"time"
+	"sync/atomic"
+
+	"github.com/ataul443/sweep/internal/entry"
+	"github.com/ataul443/sweep/internal/sketch"
 )
 
 type shard struct {
+	// stats is kept as the first field so its 64-bit counters stay
+	// aligned for atomic access on 32-bit platforms.
+	stats shardStats
+
 	hashIndexBucket map[uint64]int
 
 	queue *entry.Queue
 
 	maxSize int
 
+	// budget is shared by all shards of the sweep, and accounts the
+	// capacity of their queues.
+	budget *memoryBudget
+
+	maxEntrySize int
+
+	// compactionRatio is the ratio of dead bytes from which the shard
+	// gets compacted, by cleanup and before a full shard evicts anything.
+	compactionRatio float64
+
+	// deadBytes is the number of bytes held by tombstones in the queue.
+	deadBytes int
+
+	// underutilizedCycles is the number of cleanups in a row the shard
+	// stayed underutilized for.
+	underutilizedCycles int
+
+	evictionPolicy EvictionPolicy
+
+	// admission is nil unless the TinyLFU admission policy is used.
+	admission *sketch.CountMin
+
+	onRemove func(key string, value []byte, reason RemoveReason)
+
+	// version is the version of the last entry put in the shard.
+	version uint64
+
+	// removals holds entries removed under the write lock, to be
+	// reported to onRemove once the lock is released.
+	removals []removal
+
 	mu *sync.RWMutex
 }
 
-func newShard(maxSize int) *shard {
-	return &shard{
+func newShard(cfg Configuration, budget *memoryBudget) *shard {
+	sh := &shard{
 		hashIndexBucket: make(map[uint64]int),
-		queue:           entry.NewQueue(maxSize),
-		maxSize:         maxSize,
+		queue:           entry.NewQueue(cfg.MaxShardSize),
+		maxSize:         cfg.MaxShardSize,
+		budget:          budget,
+		maxEntrySize:    cfg.MaxEntrySize,
+		compactionRatio: cfg.CompactionRatio,
+		evictionPolicy:  cfg.EvictionPolicy,
+		onRemove:        cfg.OnRemove,
 		mu:              &sync.RWMutex{},
 	}
+
+	if cfg.AdmissionPolicy == AdmitTinyLFU {
+		sh.admission = sketch.New(sketchWidth(cfg.MaxShardSize))
+	}
+
+	// Every shard gets its initial capacity, even past the budget.
+	budget.add(sh.queue.Capacity())
+
+	return sh
 }
 
-func (sh *shard) put(hashedKey uint64, timestamp int64, val []byte) error {
+// putCondition decides whether a put goes ahead, given whether the
+// key has a live entry and the version of that entry.
+type putCondition func(exists bool, version uint64) bool
+
+// put stores the entry, if cond is nil or allows it. It returns the
+// change in the number of entries of the shard, which is negative when
+// making room for the entry evicted more entries than it added, and
+// errNotApplied if cond didn't allow the put.
+func (sh *shard) put(e entry.Entry, cond putCondition) (int, error) {
 	sh.mu.Lock()
-	defer sh.mu.Unlock()
+	added, err := sh.putLocked(e, cond)
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
 
-	spaceExist := sh.queue.SpaceAvailable(entry.FrameLen(val))
-	if !spaceExist {
-		err := sh.queue.Grow()
+	sh.notifyRemovals(removed)
+	return added, err
+}
+
+func (sh *shard) putLocked(e entry.Entry, cond putCondition) (int, error) {
+	exists := false
+	live := false
+	var version uint64
+
+	if idx, ok := sh.hashIndexBucket[e.HashedKey]; ok {
+		frame, err := sh.queue.PeekAt(idx)
 		if err != nil {
-			return err
+			return 0, err
+		}
+
+		storedKey, err := entry.KeyView(frame)
+		if err != nil {
+			return 0, err
+		}
+
+		exists = bytes.Equal(storedKey, e.Key)
+		if !exists {
+			atomic.AddUint64(&sh.stats.collisions, 1)
+		} else if cond != nil {
+			expired, err := entry.ExpiredAt(frame, e.Timestamp)
+			if err != nil {
+				return 0, err
+			}
+
+			live = !expired
+			if version, err = entry.VersionFromFrame(frame); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if cond != nil && !cond(live, version) {
+		return 0, errNotApplied
+	}
+
+	if sh.admission != nil {
+		sh.admission.Increment(e.HashedKey)
+	}
+
+	evicted, err := sh.makeRoom(e.HashedKey, !exists, entry.FrameLen(e.Key, e.Value))
+	if err != nil {
+		return -evicted, err
+	}
+
+	// Unless making room evicted it, the entry the key points at is
+	// being replaced, or evicted if it belongs to a colliding key, and
+	// the number of entries stays the same.
+	var old entry.Frame
+	idx, replaced := sh.hashIndexBucket[e.HashedKey]
+	if replaced {
+		old, err = sh.queue.PeekAt(idx)
+		if err != nil {
+			return -evicted, err
+		}
+	}
+
+	sh.version++
+	e.Version = sh.version
+
+	idx, err = sh.queue.Push(e)
+	if err != nil {
+		return -evicted, err
+	}
+
+	if replaced {
+		reason := Replaced
+		if !exists {
+			reason = Evicted
+		}
+
+		sh.recordRemoval(old, reason)
+
+		// Pushing doesn't move frames, so old is still in place. It
+		// stays in the queue until cleanup reclaims it, mark it so its
+		// bytes count as dead.
+		sh.markTombstone(old)
+	}
+
+	sh.hashIndexBucket[e.HashedKey] = idx
+	if replaced {
+		return -evicted, nil
+	}
+
+	return 1 - evicted, nil
+}
+
+// makeRoom ensures the queue has space for a frame of length size,
+// growing the queue first. Once the queue can't grow anymore, within
+// MaxShardSize and the memory budget, it is
+// compacted if enough of it is dead, then entries are evicted according
+// to the eviction policy. A new key has to pass the admission policy
+// before it may evict anything. It returns the number of live entries
+// evicted.
+func (sh *shard) makeRoom(hashedKey uint64, newKey bool, size int) (int, error) {
+	evicted := 0
+	admitted := !newKey || sh.admission == nil
+	compacted := false
+
+	for !sh.queue.SpaceAvailable(size) {
+		err := sh.grow()
+		if err == nil {
+			continue
+		}
+
+		if err != entry.ErrQueueMaxSizeReaced && err != ErrMemoryBudgetExceeded {
+			return evicted, err
+		}
+
+		// Reclaiming dead frames may make room without evicting
+		// anything.
+		if !compacted {
+			compacted = true
+			if sh.compactIfDeadLocked(sh.compactionRatio) {
+				continue
+			}
+		}
+
+		if sh.evictionPolicy == NoEviction {
+			return evicted, err
+		}
+
+		if size > sh.queue.Capacity() {
+			// Evicting everything still wouldn't make it fit.
+			return evicted, ErrEntryTooLarge
+		}
+
+		if !admitted {
+			ok, err := sh.admit(hashedKey)
+			if err != nil {
+				return evicted, err
+			}
+
+			if !ok {
+				atomic.AddUint64(&sh.stats.rejected, 1)
+				return evicted, ErrEntryRejected
+			}
+
+			atomic.AddUint64(&sh.stats.admitted, 1)
+			admitted = true
+		}
+
+		n, err := sh.evict()
+		evicted += n
+		atomic.AddUint64(&sh.stats.evictions, uint64(n))
+
+		if err != nil {
+			return evicted, err
+		}
+	}
+
+	return evicted, nil
+}
+
+// remapIndexes moves indexes of the hash index bucket to where Grow
+// relocated their frames.
+func (sh *shard) remapIndexes(remap entry.Remap) {
+	for hk, idx := range sh.hashIndexBucket {
+		sh.hashIndexBucket[hk] = remap.Index(idx)
+	}
+}
+
+// admit reports whether the key is accessed more frequently than the
+// next eviction victim.
+func (sh *shard) admit(hashedKey uint64) (bool, error) {
+	err := sh.popTombstones()
+	if err != nil {
+		return false, err
+	}
+
+	victim, ok, err := sh.victim()
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		return true, nil
+	}
+
+	return sh.admission.Estimate(hashedKey) > sh.admission.Estimate(victim), nil
+}
+
+// victim returns the hashed key of the live entry evict would remove
+// next, and false if the queue holds no live entry. Tombstones and
+// overwritten frames hold no entry, and with CLOCK referenced entries
+// get a second chance first, so they are skipped.
+func (sh *shard) victim() (uint64, bool, error) {
+	var victim, first uint64
+	found, live := false, false
+
+	var walkErr error
+	sh.queue.Walk(func(idx int, frame entry.Frame) bool {
+		if frame.IsTombstone() {
+			return true
+		}
+
+		hk, err := entry.HashedKeyFromFrame(frame)
+		if err != nil {
+			walkErr = err
+			return false
+		}
+
+		if !sh.isCurrent(hk, idx) {
+			return true
+		}
+
+		if !live {
+			first, live = hk, true
+		}
+
+		if sh.evictionPolicy == EvictCLOCK && frame.IsReferenced() {
+			return true
+		}
+
+		victim, found = hk, true
+		return false
+	})
+
+	if walkErr != nil {
+		return 0, false, walkErr
+	}
+
+	// Once every entry is referenced, CLOCK clears their bits in a pass
+	// over the queue and evicts the first one.
+	if !found && live {
+		return first, true, nil
+	}
+
+	return victim, found, nil
+}
+
+// evict removes entries from the front of the queue according to the
+// eviction policy and returns the number of live entries evicted.
+func (sh *shard) evict() (int, error) {
+	if sh.evictionPolicy == EvictCLOCK {
+		return sh.evictUnreferenced()
+	}
+
+	return sh.evictOldest()
+}
+
+// evictOldest pops the frame at the front of the queue and returns 1
+// if it held a live entry, 0 otherwise.
+func (sh *shard) evictOldest() (int, error) {
+	idx := sh.queue.FrontIndex()
+
+	frame, err := sh.queue.Pop()
+	if err != nil {
+		return 0, err
+	}
+
+	if frame.IsTombstone() {
+		sh.deadBytes -= frame.Len()
+		return 0, nil
+	}
+
+	hk, err := entry.HashedKeyFromFrame(frame)
+	if err != nil {
+		return 0, err
+	}
+
+	// An overwritten frame holds no entry anymore, the key has moved
+	// on to a newer frame.
+	if !sh.isCurrent(hk, idx) {
+		return 0, nil
+	}
+
+	sh.recordRemoval(frame, Evicted)
+
+	delete(sh.hashIndexBucket, hk)
+	return 1, nil
+}
+
+// evictUnreferenced gives referenced entries at the front of the queue
+// a second chance, by clearing their reference bit and moving their
+// frames to the back of the queue, until it finds an unreferenced one
+// to evict. Every moved frame has its bit cleared, so it terminates
+// after at most one pass over the queue.
+func (sh *shard) evictUnreferenced() (int, error) {
+	for {
+		frame, err := sh.queue.Front()
+		if err != nil {
+			return 0, err
+		}
+
+		if frame.IsTombstone() || !frame.IsReferenced() {
+			return sh.evictOldest()
 		}
+
+		hk, err := entry.HashedKeyFromFrame(frame)
+		if err != nil {
+			return 0, err
+		}
+
+		// Only the frame the map points at is worth keeping, an
+		// overwritten one must not come back to life.
+		if !sh.isCurrent(hk, sh.queue.FrontIndex()) {
+			return sh.evictOldest()
+		}
+
+		frame, err = sh.queue.Pop()
+		if err != nil {
+			return 0, err
+		}
+
+		frame.ClearReferenced()
+
+		idx, err := sh.queue.PushFrame(frame)
+		if err != nil {
+			// Popping the frame freed enough space for it, so this
+			// should never happen.
+			sh.recordRemoval(frame, Evicted)
+			delete(sh.hashIndexBucket, hk)
+			return 1, nil
+		}
+
+		sh.hashIndexBucket[hk] = idx
+	}
+}
+
+// get returns the value of the key, treating entries expired at unix
+// time now in nanoseconds as missing even if cleanup hasn't removed
+// them yet.
+func (sh *shard) get(hashedKey uint64, key []byte, now int64) (entry.Entry, error) {
+	e, idx, referenced, err := sh.lookup(hashedKey, key, now)
+	if err != nil {
+		if err == ErrEntryNotFound {
+			atomic.AddUint64(&sh.stats.misses, 1)
+		}
+
+		return entry.Entry{}, err
+	}
+
+	atomic.AddUint64(&sh.stats.hits, 1)
+
+	if sh.evictionPolicy == EvictCLOCK && !referenced {
+		sh.markReferenced(hashedKey, idx)
+	}
+
+	return e, nil
+}
+
+func (sh *shard) lookup(hashedKey uint64, key []byte, now int64) (entry.Entry, int, bool, error) {
+	if sh.admission != nil {
+		sh.admission.Increment(hashedKey)
+	}
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	return sh.lookupLocked(hashedKey, key, now)
+}
+
+// lookupLocked is lookup for callers holding the lock.
+func (sh *shard) lookupLocked(hashedKey uint64, key []byte, now int64) (entry.Entry, int, bool, error) {
+	idx, ok := sh.hashIndexBucket[hashedKey]
+	if !ok {
+		return entry.Entry{}, 0, false, ErrEntryNotFound
+	}
+
+	frame, err := sh.queue.PeekAt(idx)
+	if err != nil {
+		return entry.Entry{}, 0, false, err
+	}
+
+	// Only the value is handed out, the stored key is compared where
+	// it lies.
+	storedKey, err := entry.KeyView(frame)
+	if err != nil {
+		return entry.Entry{}, 0, false, err
+	}
+
+	if !bytes.Equal(storedKey, key) {
+		atomic.AddUint64(&sh.stats.collisions, 1)
+		return entry.Entry{}, 0, false, ErrEntryNotFound
+	}
+
+	expired, err := entry.ExpiredAt(frame, now)
+	if err != nil {
+		return entry.Entry{}, 0, false, err
+	}
+
+	if expired {
+		atomic.AddUint64(&sh.stats.expiredReads, 1)
+		return entry.Entry{}, 0, false, ErrEntryNotFound
+	}
+
+	e, err := entry.GetEntryWithoutKey(frame)
+	if err != nil {
+		return entry.Entry{}, 0, false, err
+	}
+
+	return e, idx, frame.IsReferenced(), nil
+}
+
+// markReferenced sets the reference bit of the frame at idx. Setting it
+// writes into the queue, so it is done under the write lock, and only
+// once per eviction pass since reads check the bit first.
+func (sh *shard) markReferenced(hashedKey uint64, idx int) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.markReferencedLocked(hashedKey, idx)
+}
+
+func (sh *shard) markReferencedLocked(hashedKey uint64, idx int) {
+	// The entry may have been replaced or removed in between.
+	if !sh.isCurrent(hashedKey, idx) {
+		return
+	}
+
+	frame, err := sh.queue.PeekAt(idx)
+	if err != nil {
+		return
 	}
 
-	idx, err := sh.queue.Push(hashedKey, timestamp, val)
+	frame.MarkReferenced()
+}
+
+// del removes the entry of the key. Like get, it treats entries
+// expired at unix time now in nanoseconds as missing, they are left
+// for cleanup to remove.
+func (sh *shard) del(hashedKey uint64, key []byte, now int64) error {
+	sh.mu.Lock()
+	err := sh.delLocked(hashedKey, key, now)
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
+
+	sh.notifyRemovals(removed)
+	return err
+}
+
+func (sh *shard) delLocked(hashedKey uint64, key []byte, now int64) error {
+	_, frame, err := sh.frameOf(hashedKey, key)
 	if err != nil {
 		return err
 	}
 
-	sh.hashIndexBucket[hashedKey] = idx
+	expired, err := entry.ExpiredAt(frame, now)
+	if err != nil {
+		return err
+	}
+
+	if expired {
+		return ErrEntryNotFound
+	}
+
+	sh.recordRemoval(frame, Deleted)
+
+	// The frame stays in the queue until cleanup reclaims it, mark it
+	// so it is never mistaken for a live entry.
+	sh.markTombstone(frame)
+
+	delete(sh.hashIndexBucket, hashedKey)
 	return nil
 }
 
-func (sh *shard) get(hashedKey uint64) ([]byte, error) {
+// isCurrent reports whether the frame at idx is the one the hash index
+// bucket points the hashed key at, rather than an overwritten one.
+func (sh *shard) isCurrent(hashedKey uint64, idx int) bool {
+	cur, ok := sh.hashIndexBucket[hashedKey]
+	return ok && cur == idx
+}
+
+// expiry returns the expiry of the key's entry, unless the entry is
+// expired at unix time now in nanoseconds already. Unlike reads, it
+// doesn't count as an access of the entry.
+func (sh *shard) expiry(hashedKey uint64, key []byte, now int64) (int64, error) {
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
 
+	_, frame, err := sh.frameOf(hashedKey, key)
+	if err != nil {
+		return 0, err
+	}
+
+	expiry, err := entry.ExpiryFromFrame(frame)
+	if err != nil {
+		return 0, err
+	}
+
+	if expiry != 0 && expiry <= now {
+		return 0, ErrEntryNotFound
+	}
+
+	return expiry, nil
+}
+
+// setExpiry overwrites the expiry of the key's entry in place, unless
+// the entry is expired at unix time now in nanoseconds already.
+func (sh *shard) setExpiry(hashedKey uint64, key []byte, expiry, now int64) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	_, frame, err := sh.frameOf(hashedKey, key)
+	if err != nil {
+		return err
+	}
+
+	expired, err := entry.ExpiredAt(frame, now)
+	if err != nil {
+		return err
+	}
+
+	if expired {
+		return ErrEntryNotFound
+	}
+
+	frame.SetExpiry(expiry)
+	return nil
+}
+
+// frameOf returns the index and the frame of the key's entry. Callers
+// must hold the lock.
+func (sh *shard) frameOf(hashedKey uint64, key []byte) (int, entry.Frame, error) {
 	idx, ok := sh.hashIndexBucket[hashedKey]
 	if !ok {
-		return nil, ErrEntryNotFound
+		return 0, nil, ErrEntryNotFound
 	}
 
 	frame, err := sh.queue.PeekAt(idx)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 
-	val, err := entry.ValFromFrame(frame)
+	storedKey, err := entry.KeyView(frame)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
+	}
+
+	if !bytes.Equal(storedKey, key) {
+		atomic.AddUint64(&sh.stats.collisions, 1)
+		return 0, nil, ErrEntryNotFound
 	}
 
-	return val, nil
+	return idx, frame, nil
+}
+
+// liveItems returns copies of entries in the shard which aren't
+// deleted, overwritten or expired at unix time now in nanoseconds.
+func (sh *shard) liveItems(now int64) []Item {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	items := make([]Item, 0, len(sh.hashIndexBucket))
+	sh.queue.Walk(func(idx int, frame entry.Frame) bool {
+		if frame.IsTombstone() {
+			return true
+		}
+
+		e, err := entry.GetEntryFromFrame(frame)
+		if err != nil {
+			return false
+		}
+
+		// The map points at the latest frame of a key only.
+		if !sh.isCurrent(e.HashedKey, idx) {
+			return true
+		}
+
+		if e.ExpiredAt(now) {
+			return true
+		}
+
+		items = append(items, Item{Key: string(e.Key), Value: e.Value})
+		return true
+	})
+
+	return items
+}
+
+// liveFrames appends frames of entries in the shard which aren't
+// deleted, overwritten or expired at unix time now in nanoseconds to
+// buf and returns the extended buffer.
+func (sh *shard) liveFrames(now int64, buf []byte) []byte {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	sh.queue.Walk(func(idx int, frame entry.Frame) bool {
+		if frame.IsTombstone() {
+			return true
+		}
+
+		hk, err := entry.HashedKeyFromFrame(frame)
+		if err != nil {
+			return false
+		}
+
+		if !sh.isCurrent(hk, idx) {
+			return true
+		}
+
+		expired, err := entry.ExpiredAt(frame, now)
+		if err != nil {
+			return false
+		}
+
+		if !expired {
+			buf = append(buf, frame...)
+		}
+
+		return true
+	})
+
+	return buf
+}
+
+func (sh *shard) usage() ShardStats {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	return ShardStats{
+		BytesUsed: sh.queue.Size(),
+		Capacity:  sh.queue.Capacity(),
+	}
 }
 
-func (sh *shard) cleanupExpiredEntries(entryLifetime time.Duration) (int, error) {
+// cleanupExpiredEntries removes entries expired at unix time now in
+// nanoseconds and returns how many were removed. Entries carry their
+// own expiry, so expired frames can sit behind live ones in the queue.
+// The whole queue is scanned to turn expired frames into tombstones,
+// then tombstones at the front of the queue are popped, and the queue
+// is compacted once tombstones behind live entries pile up.
+func (sh *shard) cleanupExpiredEntries(now int64) (int, error) {
 	sh.mu.Lock()
-	defer sh.mu.Unlock()
+	n, err := sh.cleanupExpiredEntriesLocked(now)
+	removed := sh.takeRemovals()
+	sh.mu.Unlock()
+
+	sh.notifyRemovals(removed)
+	return n, err
+}
+
+func (sh *shard) cleanupExpiredEntriesLocked(now int64) (int, error) {
+	expiredCount := 0
+
+	var walkErr error
+	sh.queue.Walk(func(idx int, frame entry.Frame) bool {
+		if frame.IsTombstone() {
+			return true
+		}
+
+		expired, err := entry.ExpiredAt(frame, now)
+		if err != nil {
+			walkErr = err
+			return false
+		}
+
+		if !expired {
+			return true
+		}
+
+		hk, err := entry.HashedKeyFromFrame(frame)
+		if err != nil {
+			walkErr = err
+			return false
+		}
+
+		sh.markTombstone(frame)
+
+		// An overwritten frame expiring doesn't affect the newer
+		// frame of its key.
+		if !sh.isCurrent(hk, idx) {
+			return true
+		}
 
-	poppedCount := 0
+		sh.recordRemoval(frame, Expired)
 
+		delete(sh.hashIndexBucket, hk)
+		expiredCount += 1
+		return true
+	})
+
+	atomic.AddUint64(&sh.stats.expirations, uint64(expiredCount))
+	if walkErr != nil {
+		return expiredCount, walkErr
+	}
+
+	if err := sh.popTombstones(); err != nil {
+		return expiredCount, err
+	}
+
+	// Popping only reclaims tombstones at the front of the queue, the
+	// ones behind a live entry are reclaimed by compacting.
+	sh.compactIfDeadLocked(sh.compactionRatio)
+	return expiredCount, nil
+}
+
+// popTombstones reclaims the space of dead frames at the front of
+// the queue. Their entries were already removed from the map and the
+// entries count.
+func (sh *shard) popTombstones() error {
 	for {
 		frame, err := sh.queue.Front()
 		if err != nil {
 			if err == entry.ErrQueueEmpty {
-				return poppedCount, nil
+				return nil
 			}
 
-			return poppedCount, err
+			return err
+		}
+
+		if !frame.IsTombstone() {
+			return nil
 		}
 
-		hk, tm, _, err := entry.GetEntryFromFrame(frame)
+		_, err = sh.queue.Pop()
 		if err != nil {
-			return poppedCount, err
+			return err
 		}
 
-		if time.Since(time.Unix(tm, 0)) > entryLifetime {
-			_, err = sh.queue.Pop()
-			if err != nil {
-				return poppedCount, err
-			}
+		sh.deadBytes -= frame.Len()
+	}
+}
 
-			// delete the key from map
-			delete(sh.hashIndexBucket, hk)
-			poppedCount += 1
+// markTombstone marks the frame as deleted in place and accounts its
+// bytes as dead until they are reclaimed.
+func (sh *shard) markTombstone(frame entry.Frame) {
+	frame.MarkTombstone()
+	sh.deadBytes += frame.Len()
+}
 
-		} else {
-			break
-		}
+// sketchWidth returns the width of a shard's admission sketch. It aims
+// for a counter per 32 bytes of the shard, roughly a counter per small
+// entry, within sane bounds.
+func sketchWidth(maxShardSize int) int {
+	width := maxShardSize / 32
+	if width < minSketchWidth {
+		width = minSketchWidth
+	}
+
+	if width > maxSketchWidth {
+		width = maxSketchWidth
 	}
 
-	return poppedCount, nil
+	return width
 }
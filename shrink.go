@@ -0,0 +1,49 @@
+package sweep
+
+import "sync/atomic"
+
+// shrinkUnderutilizedShards halves the capacity of shards whose used
+// bytes stayed below threshold of their capacity for cycles calls in a
+// row, and returns how many it shrank.
+func (s *Sweep) shrinkUnderutilizedShards(threshold float64, cycles int) int {
+	shrunk := 0
+	for _, sh := range s.shards {
+		if sh.shrinkIfUnderutilized(threshold, cycles) {
+			shrunk++
+		}
+	}
+
+	return shrunk
+}
+
+// shrinkIfUnderutilized counts the calls the shard's used bytes stay
+// below threshold of its capacity for, and halves its capacity once
+// they did for cycles calls in a row. It reports whether it shrank the
+// shard.
+func (sh *shard) shrinkIfUnderutilized(threshold float64, cycles int) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if float64(sh.queue.Size()) >= threshold*float64(sh.queue.Capacity()) {
+		sh.underutilizedCycles = 0
+		return false
+	}
+
+	sh.underutilizedCycles++
+	if sh.underutilizedCycles < cycles {
+		return false
+	}
+
+	sh.underutilizedCycles = 0
+
+	// The queue may be at its initial size already.
+	remap, err := sh.queue.Shrink()
+	if err != nil {
+		return false
+	}
+
+	sh.remapIndexes(remap)
+	sh.budget.add(-sh.queue.Capacity())
+	atomic.AddUint64(&sh.stats.shrinks, 1)
+	return true
+}
@@ -0,0 +1,59 @@
+package sweep
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweepShrink(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, MaxShardSize: 1024 * 1024, CleanupInterval: time.Hour,
+		ShrinkUtilization: 0.25, ShrinkAfterCycles: 2})
+	defer cache.Close()
+
+	// A burst of keys grows the shard, deleting all but the newest ones
+	// and cleaning up leaves it mostly empty.
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+
+		err := cache.Put(key, []byte("valueof"+key))
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+	}
+
+	for i := 0; i < 1990; i++ {
+		err := cache.Delete(fmt.Sprintf("pokemon%d", i))
+		assert.NoError(t, err, "delete should be successful")
+	}
+
+	_, err := cache.cleanupExpiredEntries()
+	assert.NoError(t, err, "cleanup should be successful")
+
+	peak := cache.Stats().Shards[0].Capacity
+
+	shrunk := cache.shrinkUnderutilizedShards(0.25, 2)
+	assert.Equalf(t, 0, shrunk, "expected %d shrunk shards after one cycle, got %d", 0, shrunk)
+
+	shrunk = cache.shrinkUnderutilizedShards(0.25, 2)
+	assert.Equalf(t, 1, shrunk, "expected %d shrunk shards after two cycles, got %d", 1, shrunk)
+	assert.Equalf(t, peak/2, cache.Stats().Shards[0].Capacity, "expected capacity %d, got %d",
+		peak/2, cache.Stats().Shards[0].Capacity)
+
+	for i := 0; i < 32; i++ {
+		cache.shrinkUnderutilizedShards(0.25, 2)
+	}
+
+	stats := cache.Stats()
+	assert.Truef(t, stats.Shards[0].Capacity < peak/8, "expected capacity below %d, got %d",
+		peak/8, stats.Shards[0].Capacity)
+	assert.Truef(t, stats.Shrinks >= 3, "expected at least %d shrinks, got %d", 3, stats.Shrinks)
+
+	for i := 1990; i < 2000; i++ {
+		key := fmt.Sprintf("pokemon%d", i)
+
+		val, err := cache.Get(key)
+		assert.NoErrorf(t, err, "get should be successful for %s", key)
+		assert.Equalf(t, []byte("valueof"+key), val, "expected %s, got %s", "valueof"+key, val)
+	}
+}
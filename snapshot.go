@@ -0,0 +1,185 @@
+package sweep
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// Snapshot layout:
+//
+//	magic    [4]byte "SWEP"
+//	version  uint32
+//	frames   live entry frames in the entry.Frame encoding, each one
+//	         starting with its own length
+//	end      uint32 zero
+//	checksum uint32 CRC-32 (IEEE) of everything before it
+//
+// All integers are little endian.
+const snapshotVersion = 2
+
+var snapshotMagic = [4]byte{'S', 'W', 'E', 'P'}
+
+// SaveTo writes a snapshot of live entries of the sweep to w. Entries
+// keep their original insertion time and expiry. Shards are copied one
+// at a time under their read lock, and written to w outside of it.
+func (s *Sweep) SaveTo(w io.Writer) error {
+	if s.isClosed() {
+		return ErrClosed
+	}
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	mw := io.MultiWriter(bw, crc)
+
+	header := make([]byte, 8)
+	copy(header, snapshotMagic[:])
+	binary.LittleEndian.PutUint32(header[4:], snapshotVersion)
+
+	if _, err := mw.Write(header); err != nil {
+		return err
+	}
+
+	var frames []byte
+	for _, sh := range s.shards {
+		frames = sh.liveFrames(time.Now().UnixNano(), frames[:0])
+
+		if _, err := mw.Write(frames); err != nil {
+			return err
+		}
+	}
+
+	end := make([]byte, 4)
+	if _, err := mw.Write(end); err != nil {
+		return err
+	}
+
+	checksum := make([]byte, 4)
+	binary.LittleEndian.PutUint32(checksum, crc.Sum32())
+	if _, err := bw.Write(checksum); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// LoadFrom returns a sweep configured to given configuration and filled
+// with entries of the snapshot read from r. Entries expired by now are
+// skipped, the others expire at their original expiry.
+func LoadFrom(r io.Reader, cfg Configuration) (*Sweep, error) {
+	s := New(cfg)
+
+	err := s.loadSnapshot(bufio.NewReader(r))
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Sweep) loadSnapshot(r io.Reader) error {
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(r, crc)
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(tr, header); err != nil {
+		return snapshotReadErr(err)
+	}
+
+	if string(header[:4]) != string(snapshotMagic[:]) {
+		return ErrInvalidSnapshot
+	}
+
+	if binary.LittleEndian.Uint32(header[4:]) != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+
+	maxFrameLen := math.MaxInt32
+	if s.cfg.MaxShardSize != 0 {
+		maxFrameLen = s.cfg.MaxShardSize
+	}
+
+	// Entries are kept aside until the checksum is verified, so a corrupt
+	// snapshot puts nothing into the sweep.
+	var entries []entry.Entry
+	var frame []byte
+	frameLenBuf := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(tr, frameLenBuf); err != nil {
+			return snapshotReadErr(err)
+		}
+
+		frameLen := int(binary.LittleEndian.Uint32(frameLenBuf))
+		if frameLen == 0 {
+			break
+		}
+
+		if frameLen < entry.FrameLen(nil, nil) || frameLen > maxFrameLen {
+			return ErrInvalidSnapshot
+		}
+
+		if cap(frame) < frameLen {
+			frame = make([]byte, frameLen)
+		}
+		frame = frame[:frameLen]
+
+		copy(frame, frameLenBuf)
+		if _, err := io.ReadFull(tr, frame[4:]); err != nil {
+			return snapshotReadErr(err)
+		}
+
+		e, err := entry.GetEntryFromFrame(frame)
+		if err != nil {
+			return ErrInvalidSnapshot
+		}
+
+		entries = append(entries, e)
+	}
+
+	sum := crc.Sum32()
+
+	checksum := make([]byte, 4)
+	if _, err := io.ReadFull(r, checksum); err != nil {
+		return snapshotReadErr(err)
+	}
+
+	if binary.LittleEndian.Uint32(checksum) != sum {
+		return ErrInvalidSnapshot
+	}
+
+	for _, e := range entries {
+		if err := s.restore(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore puts an entry read from a snapshot into the sweep, as it
+// was at the time of the snapshot.
+func (s *Sweep) restore(e entry.Entry) error {
+	if e.ExpiredAt(time.Now().UnixNano()) {
+		return nil
+	}
+
+	if len(e.Value) > s.cfg.MaxEntrySize {
+		return ErrEntryTooLarge
+	}
+
+	return s.put(e, nil)
+}
+
+func snapshotReadErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidSnapshot
+	}
+
+	return err
+}
@@ -0,0 +1,171 @@
+package sweep
+
+import (
+	"sync/atomic"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// Stats represents counters collected by sweep since it was created.
+type Stats struct {
+	// Hits is the number of times Get found the key.
+	Hits uint64
+
+	// Misses is the number of times Get didn't find the key. It
+	// includes collisions and expired reads.
+	Misses uint64
+
+	// Collisions is the number of times two different keys hashed to
+	// the same value. A collision on Get or Delete is reported as a
+	// miss, a collision on Put replaces the other key's entry.
+	Collisions uint64
+
+	// ExpiredReads is the number of times Get found an entry which
+	// was expired but not cleaned up yet, and reported it as a miss.
+	ExpiredReads uint64
+
+	// Expirations is the number of expired entries removed by cleanup.
+	Expirations uint64
+
+	// Evictions is the number of live entries evicted to make room
+	// for new ones.
+	Evictions uint64
+
+	// Admitted is the number of new entries the admission policy let
+	// evict others.
+	Admitted uint64
+
+	// Rejected is the number of new entries the admission policy
+	// didn't let evict others.
+	Rejected uint64
+
+	// Compactions is the number of times a shard was compacted.
+	Compactions uint64
+
+	// Shrinks is the number of times a shard's capacity was halved.
+	Shrinks uint64
+
+	// PutFailures counts failed puts by cause. Puts rejected by the
+	// admission policy are counted in Rejected only.
+	PutFailures PutFailures
+
+	// BytesUsed is the number of bytes held by frames across all
+	// shards.
+	BytesUsed int
+
+	// Capacity is the number of bytes allocated for the queues of all
+	// shards, the usage MaxTotalBytes bounds.
+	Capacity int
+
+	// MaxTotalBytes is the configured budget for Capacity, zero if
+	// there is none.
+	MaxTotalBytes int
+
+	// Shards holds memory usage of every shard, indexed by shard.
+	Shards []ShardStats
+}
+
+// PutFailures counts failed puts by cause.
+type PutFailures struct {
+	// TooLarge is the number of puts failed with ErrEntryTooLarge.
+	TooLarge uint64
+
+	// InvalidTTL is the number of puts failed with ErrInvalidTTL.
+	InvalidTTL uint64
+
+	// ShardFull is the number of puts failed because the shard reached
+	// MaxShardSize and no eviction policy was configured.
+	ShardFull uint64
+
+	// OverBudget is the number of puts failed with
+	// ErrMemoryBudgetExceeded.
+	OverBudget uint64
+
+	// Other is the number of puts failed for any other cause.
+	Other uint64
+}
+
+// ShardStats represents memory usage of a shard.
+type ShardStats struct {
+	// BytesUsed is the number of bytes held by frames in the shard's
+	// queue, including frames of deleted and overwritten entries not
+	// reclaimed yet.
+	BytesUsed int
+
+	// Capacity is the number of bytes allocated for the shard's queue.
+	Capacity int
+}
+
+// shardStats holds counters of a single shard. Counters are updated
+// atomically, since reads only hold the shard's read lock.
+type shardStats struct {
+	hits         uint64
+	misses       uint64
+	collisions   uint64
+	expiredReads uint64
+	expirations  uint64
+	evictions    uint64
+	admitted     uint64
+	rejected     uint64
+	compactions  uint64
+	shrinks      uint64
+
+	putTooLarge   uint64
+	putInvalidTTL uint64
+	putShardFull  uint64
+	putOverBudget uint64
+	putOther      uint64
+}
+
+// Stats returns a snapshot of counters collected across all shards.
+func (s *Sweep) Stats() Stats {
+	st := Stats{
+		MaxTotalBytes: s.cfg.MaxTotalBytes,
+		Shards:        make([]ShardStats, len(s.shards)),
+	}
+
+	for i, sh := range s.shards {
+		st.Hits += atomic.LoadUint64(&sh.stats.hits)
+		st.Misses += atomic.LoadUint64(&sh.stats.misses)
+		st.Collisions += atomic.LoadUint64(&sh.stats.collisions)
+		st.ExpiredReads += atomic.LoadUint64(&sh.stats.expiredReads)
+		st.Expirations += atomic.LoadUint64(&sh.stats.expirations)
+		st.Evictions += atomic.LoadUint64(&sh.stats.evictions)
+		st.Admitted += atomic.LoadUint64(&sh.stats.admitted)
+		st.Rejected += atomic.LoadUint64(&sh.stats.rejected)
+		st.Compactions += atomic.LoadUint64(&sh.stats.compactions)
+		st.Shrinks += atomic.LoadUint64(&sh.stats.shrinks)
+
+		st.PutFailures.TooLarge += atomic.LoadUint64(&sh.stats.putTooLarge)
+		st.PutFailures.InvalidTTL += atomic.LoadUint64(&sh.stats.putInvalidTTL)
+		st.PutFailures.ShardFull += atomic.LoadUint64(&sh.stats.putShardFull)
+		st.PutFailures.OverBudget += atomic.LoadUint64(&sh.stats.putOverBudget)
+		st.PutFailures.Other += atomic.LoadUint64(&sh.stats.putOther)
+
+		st.Shards[i] = sh.usage()
+		st.BytesUsed += st.Shards[i].BytesUsed
+		st.Capacity += st.Shards[i].Capacity
+	}
+
+	return st
+}
+
+// countPutFailure records a failed put by its cause.
+func (st *shardStats) countPutFailure(err error) {
+	switch err {
+	case ErrEntryRejected:
+		// Already counted by the admission policy.
+	case errNotApplied:
+		// Not a failure, the write was skipped on purpose.
+	case ErrEntryTooLarge:
+		atomic.AddUint64(&st.putTooLarge, 1)
+	case ErrInvalidTTL:
+		atomic.AddUint64(&st.putInvalidTTL, 1)
+	case entry.ErrQueueMaxSizeReaced:
+		atomic.AddUint64(&st.putShardFull, 1)
+	case ErrMemoryBudgetExceeded:
+		atomic.AddUint64(&st.putOverBudget, 1)
+	default:
+		atomic.AddUint64(&st.putOther, 1)
+	}
+}
@@ -1,10 +1,13 @@
 package sweep
 
 import (
+	"math"
 	"sync/atomic"
 	"time"
 
 	"github.com/cespare/xxhash"
+
+	"github.com/ataul443/sweep/internal/entry"
 )
 
 type Sweep struct {
@@ -12,48 +15,128 @@ type Sweep struct {
 
 	shards []*shard
 
+	// budget accounts the capacity of the queues of all shards against
+	// MaxTotalBytes.
+	budget *memoryBudget
+
 	closeCh chan struct{}
 
 	entriesCount uint64
+
+	loads loadGroup
+
+	loadErrs loadErrCache
+
+	// refresh is nil unless refresh-ahead is enabled.
+	refresh *refresher
 }
 
 // Get retrieves value associated with the key from the sweep.
+// Expired entries are never returned, even if they haven't been
+// cleaned up yet.
 func (s *Sweep) Get(key string) (value []byte, err error) {
 	if s.isClosed() {
 		err = ErrClosed
 		return
 	}
 
-	keyHash := s.hashKey(key)
-	shardAlloted := s.shards[s.getShardIndex(keyHash)]
+	now := time.Now().UnixNano()
 
-	val, err := shardAlloted.get(keyHash)
+	e, err := s.getEntry(key, now)
 	if err != nil {
 		return nil, err
 	}
 
-	return val, nil
+	s.maybeRefresh(key, e.Timestamp, e.Expiry, now)
+	return e.Value, nil
+}
+
+// GetStale retrieves value associated with the key from the sweep,
+// like Get, but keeps returning the value for StaleGracePeriod after
+// the entry expired. The returned stale flag tells whether the value
+// is expired already.
+func (s *Sweep) GetStale(key string) (value []byte, stale bool, err error) {
+	if s.isClosed() {
+		err = ErrClosed
+		return
+	}
+
+	now := time.Now().UnixNano()
+
+	e, err := s.getEntry(key, now-int64(s.cfg.StaleGracePeriod))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return e.Value, e.ExpiredAt(now), nil
 }
 
 // Put inserts the value associated with the key into the sweep.
+// The entry expires after the configured EntryLifetime.
 func (s *Sweep) Put(key string, value []byte) error {
+	return s.PutWithTTL(key, value, s.cfg.EntryLifetime)
+}
+
+// PutWithTTL inserts the value associated with the key into the sweep.
+// The entry expires after ttl instead of the configured EntryLifetime.
+func (s *Sweep) PutWithTTL(key string, value []byte, ttl time.Duration) error {
+	return s.PutUntil(key, value, time.Now().Add(ttl))
+}
+
+// PutUntil inserts the value associated with the key into the sweep.
+// The entry expires at deadline, or in the year 2262 if deadline is
+// later than that.
+func (s *Sweep) PutUntil(key string, value []byte, deadline time.Time) error {
+	return s.putUntil(key, value, deadline, nil)
+}
+
+// putUntil inserts the value associated with the key into the sweep if
+// cond is nil or allows it. The entry expires at deadline.
+func (s *Sweep) putUntil(key string, value []byte, deadline time.Time, cond putCondition) error {
 	if s.isClosed() {
 		return ErrClosed
 	}
 
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
 	if len(value) > s.cfg.MaxEntrySize {
+		shardAllotted.stats.countPutFailure(ErrEntryTooLarge)
 		return ErrEntryTooLarge
 	}
 
+	now := time.Now()
+	if !deadline.After(now) {
+		shardAllotted.stats.countPutFailure(ErrInvalidTTL)
+		return ErrInvalidTTL
+	}
+
+	return s.put(entry.Entry{
+		HashedKey: keyHash,
+		Timestamp: now.UnixNano(),
+		Expiry:    unixExpiry(deadline),
+		Key:       []byte(key),
+		Value:     value,
+	}, cond)
+}
+
+// Delete removes the value associated with the key from the sweep.
+// Like Get, it treats expired entries as missing even if they haven't
+// been cleaned up yet.
+func (s *Sweep) Delete(key string) error {
+	if s.isClosed() {
+		return ErrClosed
+	}
+
 	keyHash := s.hashKey(key)
 	shardAllotted := s.shards[s.getShardIndex(keyHash)]
 
-	err := shardAllotted.put(keyHash, time.Now().Unix(), value)
+	err := shardAllotted.del(keyHash, []byte(key), time.Now().UnixNano())
 	if err != nil {
 		return err
 	}
 
-	atomic.AddUint64(&s.entriesCount, 1)
+	atomic.AddUint64(&s.entriesCount, ^uint64(0))
 	return nil
 }
 
@@ -95,23 +178,62 @@ func newSweep(cfg Configuration) *Sweep {
 	s := &Sweep{
 		cfg:     cfg,
 		closeCh: make(chan struct{}),
+		budget:  &memoryBudget{max: int64(cfg.MaxTotalBytes)},
 	}
 
 	// Initialize the shards
 	s.shards = make([]*shard, cfg.ShardsCount)
 	for i := 0; i < cfg.ShardsCount; i++ {
-		s.shards[i] = newShard(cfg.MaxShardSize)
+		s.shards[i] = newShard(cfg, s.budget)
 	}
 
 	s.startBackgroundCleanupLoop()
 
+	if cfg.Loader != nil && cfg.RefreshAheadRatio > 0 {
+		s.startRefreshWorkers()
+	}
+
 	return s
 }
 
+// getEntry returns the entry of the key unless it is expired at unix
+// time now in nanoseconds.
+func (s *Sweep) getEntry(key string, now int64) (entry.Entry, error) {
+	keyHash := s.hashKey(key)
+	shardAlloted := s.shards[s.getShardIndex(keyHash)]
+
+	return shardAlloted.get(keyHash, []byte(key), now)
+}
+
+func (s *Sweep) put(e entry.Entry, cond putCondition) error {
+	shardAllotted := s.shards[s.getShardIndex(e.HashedKey)]
+
+	added, err := shardAllotted.put(e, cond)
+
+	// Evicted entries are gone even if the put itself failed.
+	s.addEntriesCount(added)
+	if err != nil {
+		shardAllotted.stats.countPutFailure(err)
+		return err
+	}
+
+	return nil
+}
+
+// addEntriesCount adds delta, which may be negative, to the number of
+// entries in the sweep.
+func (s *Sweep) addEntriesCount(delta int) {
+	atomic.AddUint64(&s.entriesCount, uint64(delta))
+}
+
+// cleanupExpiredEntries removes entries whose stale grace period has
+// passed as well.
 func (s *Sweep) cleanupExpiredEntries() (int, error) {
+	now := time.Now().Add(-s.cfg.StaleGracePeriod).UnixNano()
+
 	totalEntriesPopped := 0
 	for _, sh := range s.shards {
-		n, err := sh.cleanupExpiredEntries(s.cfg.EntryLifetime)
+		n, err := sh.cleanupExpiredEntries(now)
 		totalEntriesPopped += n
 
 		if err != nil {
@@ -133,6 +255,12 @@ func (s *Sweep) startBackgroundCleanupLoop() {
 			case <-ticker.C:
 				n, _ := s.cleanupExpiredEntries()
 				atomic.AddUint64(&s.entriesCount, ^uint64(n-1))
+
+				if s.cfg.ShrinkUtilization > 0 {
+					s.shrinkUnderutilizedShards(s.cfg.ShrinkUtilization, s.cfg.ShrinkAfterCycles)
+				}
+
+				s.loadErrs.cleanup(time.Now().UnixNano())
 			}
 		}
 	}()
@@ -154,3 +282,18 @@ func (s *Sweep) isClosed() bool {
 func (s *Sweep) getShardIndex(hashedKey uint64) uint64 {
 	return hashedKey & (uint64(s.cfg.ShardsCount - 1))
 }
+
+// maxExpiry is the latest deadline an expiry in unix time in
+// nanoseconds can hold.
+var maxExpiry = time.Unix(0, math.MaxInt64)
+
+// unixExpiry returns deadline in unix time in nanoseconds. Deadlines
+// past maxExpiry are clamped to it rather than overflowing into the
+// past.
+func unixExpiry(deadline time.Time) int64 {
+	if deadline.After(maxExpiry) {
+		return math.MaxInt64
+	}
+
+	return deadline.UnixNano()
+}
@@ -19,3 +19,22 @@ func Benchmark1EntriesPut(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkGetInto(b *testing.B) {
+	cache := Default()
+	defer cache.Close()
+
+	err := cache.Put("pikachu", []byte("valueofpikachu"))
+	if err != nil {
+		panic(err)
+	}
+
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err = cache.GetInto("pikachu", buf[:0])
+		if err != nil {
+			panic(err)
+		}
+	}
+}
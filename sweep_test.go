@@ -1,10 +1,16 @@
 package sweep
 
 import (
+	"bytes"
+	"fmt"
+	"math"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ataul443/sweep/internal/entry"
 )
 
 type keyValPayload struct {
@@ -31,6 +37,12 @@ func TestSweep_Get(t *testing.T) {
 		assert.Equalf(t, v.Value, actualVal, "expected %s, got %s",
 			v.Value, actualVal)
 	}
+
+	// Only the value is copied out of the shard.
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = cache.Get("putKey1")
+	})
+	assert.Equalf(t, float64(1), allocs, "expected %d allocations, got %f", 1, allocs)
 }
 
 func TestSweep_Put(t *testing.T) {
@@ -99,6 +111,833 @@ func TestSweepEntryExpiration(t *testing.T) {
 	})
 }
 
+func TestSweep_Delete(t *testing.T) {
+	cache := Default()
+	defer cache.Close()
+
+	tcs := []keyValPayload{
+		{"deleteKey1", []byte("valueofdeleteKey1")},
+		{"deleteKey100", []byte("valueofdeleteKey100")},
+		{"deleteKey985", []byte("valueofdeleteKey985")},
+	}
+
+	for _, v := range tcs {
+		err := cache.Put(v.Key, v.Value)
+		assert.NoErrorf(t, err, "put should be successful with key %s", v.Key)
+	}
+
+	t.Run("deleted entry should not be found", func(t *testing.T) {
+		err := cache.Delete(tcs[0].Key)
+		assert.NoErrorf(t, err, "delete should be successful for %s", tcs[0].Key)
+
+		_, err = cache.Get(tcs[0].Key)
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		ecount := cache.EntriesCount()
+		assert.Equalf(t, len(tcs)-1, ecount, "expected entries count %d, got %d",
+			len(tcs)-1, ecount)
+	})
+
+	t.Run("deleting a missing entry should fail", func(t *testing.T) {
+		err := cache.Delete(tcs[0].Key)
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+
+	t.Run("entry put again after delete should be found", func(t *testing.T) {
+		err := cache.Put(tcs[0].Key, tcs[0].Value)
+		assert.NoErrorf(t, err, "put should be successful with key %s", tcs[0].Key)
+
+		actualVal, err := cache.Get(tcs[0].Key)
+		assert.NoErrorf(t, err, "get should be successful for %s", tcs[0].Key)
+		assert.Equalf(t, tcs[0].Value, actualVal, "expected %s, got %s",
+			tcs[0].Value, actualVal)
+	})
+}
+
+func TestSweep_PutWithTTL(t *testing.T) {
+	cfg := Configuration{ShardsCount: 1, CleanupInterval: time.Hour}
+	cache := New(cfg)
+	defer cache.Close()
+
+	t.Run("entries expiring out of insertion order should be cleaned up", func(t *testing.T) {
+		err := cache.PutWithTTL("longLived", []byte("valueoflongLived"), time.Hour)
+		assert.NoError(t, err, "put should be successful with key longLived")
+
+		err = cache.PutWithTTL("shortLived", []byte("valueofshortLived"), 50*time.Millisecond)
+		assert.NoError(t, err, "put should be successful with key shortLived")
+
+		err = cache.PutUntil("deadlined", []byte("valueofdeadlined"), time.Now().Add(50*time.Millisecond))
+		assert.NoError(t, err, "put should be successful with key deadlined")
+
+		time.Sleep(100 * time.Millisecond)
+
+		n, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+		assert.Equalf(t, 2, n, "expected %d expired entries, got %d", 2, n)
+
+		_, err = cache.Get("shortLived")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		_, err = cache.Get("deadlined")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		actualVal, err := cache.Get("longLived")
+		assert.NoError(t, err, "get should be successful for longLived")
+		assert.Equalf(t, []byte("valueoflongLived"), actualVal, "expected %s, got %s",
+			"valueoflongLived", actualVal)
+	})
+
+	t.Run("entries expired behind a live one should be reclaimed", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+		defer cache.Close()
+
+		err := cache.PutWithTTL("longLived", []byte("valueoflongLived"), time.Hour)
+		assert.NoError(t, err, "put should be successful with key longLived")
+
+		for round := 0; round < 10; round++ {
+			deadline := time.Now().Add(50 * time.Millisecond)
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("shortLived%d", i)
+
+				err := cache.PutUntil(key, []byte("valueof"+key), deadline)
+				assert.NoErrorf(t, err, "put should be successful with key %s", key)
+			}
+
+			time.Sleep(time.Until(deadline))
+
+			_, err := cache.cleanupExpiredEntries()
+			assert.NoError(t, err, "cleanup should be successful")
+		}
+
+		used := cache.shards[0].queue.Size()
+		assert.Truef(t, used < 1024, "expected less than %d bytes used, got %d", 1024, used)
+
+		_, err = cache.Get("longLived")
+		assert.NoError(t, err, "get should be successful for longLived")
+	})
+
+	t.Run("deadlines past the year 2262 should not overflow", func(t *testing.T) {
+		err := cache.PutWithTTL("forever", []byte("valueofforever"), math.MaxInt64)
+		assert.NoError(t, err, "put should be successful with key forever")
+
+		err = cache.PutUntil("millennium", []byte("valueofmillennium"),
+			time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC))
+		assert.NoError(t, err, "put should be successful with key millennium")
+
+		_, err = cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		for _, key := range []string{"forever", "millennium"} {
+			actualVal, err := cache.Get(key)
+			assert.NoErrorf(t, err, "get should be successful for %s", key)
+			assert.Equalf(t, []byte("valueof"+key), actualVal, "expected %s, got %s",
+				"valueof"+key, actualVal)
+		}
+	})
+
+	t.Run("lifetime not ending in the future should be rejected", func(t *testing.T) {
+		err := cache.PutWithTTL("pika", []byte("pika"), 0)
+		assert.EqualErrorf(t, err, ErrInvalidTTL.Error(),
+			"expected err %s, got %s", ErrInvalidTTL, err)
+
+		err = cache.PutUntil("pika", []byte("pika"), time.Now().Add(-time.Second))
+		assert.EqualErrorf(t, err, ErrInvalidTTL.Error(),
+			"expected err %s, got %s", ErrInvalidTTL, err)
+	})
+}
+
+func TestSweepLazyExpiration(t *testing.T) {
+	cfg := Configuration{ShardsCount: 1, CleanupInterval: time.Hour}
+	cache := New(cfg)
+	defer cache.Close()
+
+	err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), 50*time.Millisecond)
+	assert.NoError(t, err, "put should be successful")
+
+	_, err = cache.Get("pikachu")
+	assert.NoError(t, err, "get should be successful before expiry")
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = cache.Get("pikachu")
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+
+	expiredReads := cache.Stats().ExpiredReads
+	assert.Equalf(t, uint64(1), expiredReads, "expected expired reads %d, got %d",
+		1, expiredReads)
+
+	err = cache.Delete("pikachu")
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+
+	// Cleanup still removes the expired entry.
+	n, err := cache.cleanupExpiredEntries()
+	assert.NoError(t, err, "cleanup should be successful")
+	assert.Equalf(t, 1, n, "expected %d expired entries, got %d", 1, n)
+}
+
+func TestSweepFIFOEviction(t *testing.T) {
+	val := make([]byte, 64)
+	entriesCount := 200
+
+	t.Run("put should fail on full shard without eviction", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 1, MaxShardSize: defaultShardSize})
+		defer cache.Close()
+
+		var err error
+		for i := 0; i < entriesCount && err == nil; i++ {
+			err = cache.Put(fmt.Sprintf("key_%d", i), val)
+		}
+
+		assert.Error(t, err, "put should fail once the shard is full")
+	})
+
+	t.Run("put should evict oldest entries on full shard", func(t *testing.T) {
+		cache := New(Configuration{
+			ShardsCount:    1,
+			MaxShardSize:   defaultShardSize,
+			EvictionPolicy: EvictFIFO,
+		})
+		defer cache.Close()
+
+		for i := 0; i < entriesCount; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			err := cache.Put(key, val)
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+
+		_, err := cache.Get("key_0")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		lastKey := fmt.Sprintf("key_%d", entriesCount-1)
+		actualVal, err := cache.Get(lastKey)
+		assert.NoErrorf(t, err, "get should be successful for %s", lastKey)
+		assert.Equal(t, val, actualVal, "value should match")
+
+		ecount := cache.EntriesCount()
+		assert.Truef(t, ecount > 0 && ecount < entriesCount,
+			"expected entries count between 0 and %d, got %d", entriesCount, ecount)
+	})
+}
+
+func TestSweepCLOCKEviction(t *testing.T) {
+	val := make([]byte, 64)
+	entriesCount := 200
+
+	for _, policy := range []EvictionPolicy{EvictFIFO, EvictCLOCK} {
+		cache := New(Configuration{
+			ShardsCount:    1,
+			MaxShardSize:   defaultShardSize,
+			EvictionPolicy: policy,
+		})
+
+		err := cache.Put("hotKey", val)
+		assert.NoError(t, err, "put should be successful with key hotKey")
+
+		for i := 0; i < entriesCount; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			err := cache.Put(key, val)
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+
+			_, _ = cache.Get("hotKey")
+		}
+
+		_, err = cache.Get("hotKey")
+		if policy == EvictCLOCK {
+			assert.NoError(t, err, "hot key should survive CLOCK eviction")
+		} else {
+			assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+				"hot key should be evicted by FIFO eviction, got %s", err)
+		}
+
+		_ = cache.Close()
+	}
+}
+
+func TestSweepTinyLFUAdmission(t *testing.T) {
+	cache := New(Configuration{
+		ShardsCount:     1,
+		MaxShardSize:    defaultShardSize,
+		EvictionPolicy:  EvictFIFO,
+		AdmissionPolicy: AdmitTinyLFU,
+	})
+	defer cache.Close()
+
+	val := make([]byte, 64)
+
+	// Fill the shard with frequently read entries, until it is full.
+	var hotKeys []string
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("hotKey_%d", i)
+		if !cache.shards[0].queue.SpaceAvailable(entry.FrameLen([]byte(key), val)) {
+			break
+		}
+
+		err := cache.Put(key, val)
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+
+		hotKeys = append(hotKeys, key)
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, key := range hotKeys {
+			_, _ = cache.Get(key)
+		}
+	}
+
+	t.Run("infrequent keys should be rejected", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("oneHitWonder_%d", i)
+			err := cache.Put(key, val)
+			assert.EqualErrorf(t, err, ErrEntryRejected.Error(),
+				"expected err %s, got %s", ErrEntryRejected, err)
+		}
+
+		rejected := cache.Stats().Rejected
+		assert.Equalf(t, uint64(10), rejected, "expected rejected %d, got %d",
+			10, rejected)
+
+		_, err := cache.Get(hotKeys[0])
+		assert.NoError(t, err, "hot key should not be evicted by one hit wonders")
+	})
+
+	t.Run("frequent keys should be admitted", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_, _ = cache.Get("popularKey")
+		}
+
+		err := cache.Put("popularKey", val)
+		assert.NoError(t, err, "put should be successful with key popularKey")
+
+		admitted := cache.Stats().Admitted
+		assert.Equalf(t, uint64(1), admitted, "expected admitted %d, got %d",
+			1, admitted)
+	})
+}
+
+func TestSweepTinyLFUVictim(t *testing.T) {
+	// Four entries fill the shard, which can't grow.
+	val := make([]byte, 900)
+	newCache := func(policy EvictionPolicy) *Sweep {
+		return New(Configuration{
+			ShardsCount:     1,
+			MaxShardSize:    4 * 1024,
+			EvictionPolicy:  policy,
+			AdmissionPolicy: AdmitTinyLFU,
+		})
+	}
+
+	admitNewcomer := func(t *testing.T, cache *Sweep) {
+		for i := 0; i < 5; i++ {
+			_, _ = cache.Get("hot")
+		}
+
+		for i := 0; i < 2; i++ {
+			_, _ = cache.Get("newcomer")
+		}
+
+		err := cache.Put("newcomer", val)
+		assert.NoError(t, err, "newcomer should be admitted over cold")
+	}
+
+	t.Run("overwritten frame at the front should not be the victim", func(t *testing.T) {
+		cache := newCache(EvictFIFO)
+		defer cache.Close()
+
+		for _, key := range []string{"hot", "cold", "other", "hot"} {
+			err := cache.Put(key, val)
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+
+		admitNewcomer(t, cache)
+	})
+
+	t.Run("referenced entry at the front should not be the victim", func(t *testing.T) {
+		cache := newCache(EvictCLOCK)
+		defer cache.Close()
+
+		for _, key := range []string{"hot", "cold", "other", "another"} {
+			err := cache.Put(key, val)
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+
+		admitNewcomer(t, cache)
+	})
+}
+
+func TestSweep_Iterator(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 4, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	expected := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		val := []byte(fmt.Sprintf("valueofkey_%d", i))
+
+		err := cache.Put(key, val)
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		expected[key] = val
+	}
+
+	// Overwritten entry
+	err := cache.Put("key_1", []byte("newvalueofkey_1"))
+	assert.NoError(t, err, "put should be successful with key key_1")
+	expected["key_1"] = []byte("newvalueofkey_1")
+
+	// Deleted entry
+	err = cache.Delete("key_2")
+	assert.NoError(t, err, "delete should be successful for key_2")
+	delete(expected, "key_2")
+
+	// Expired entry
+	err = cache.PutWithTTL("key_3", []byte("valueofkey_3"), time.Millisecond)
+	assert.NoError(t, err, "put should be successful with key key_3")
+	delete(expected, "key_3")
+	time.Sleep(10 * time.Millisecond)
+
+	actual := make(map[string][]byte)
+	it := cache.Iterator()
+	for it.Next() {
+		item := it.Value()
+
+		_, seen := actual[item.Key]
+		assert.Falsef(t, seen, "key %s should be visited once", item.Key)
+
+		actual[item.Key] = item.Value
+	}
+
+	assert.Equal(t, expected, actual, "iterator should visit all live entries")
+}
+
+func TestSweepSnapshot(t *testing.T) {
+	cfg := Configuration{ShardsCount: 4, CleanupInterval: time.Hour}
+	cache := New(cfg)
+	defer cache.Close()
+
+	expected := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		val := []byte(fmt.Sprintf("valueofkey_%d", i))
+
+		err := cache.Put(key, val)
+		assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		expected[key] = val
+	}
+
+	err := cache.Delete("key_0")
+	assert.NoError(t, err, "delete should be successful for key_0")
+	delete(expected, "key_0")
+
+	err = cache.PutWithTTL("shortLived", []byte("valueofshortLived"), 100*time.Millisecond)
+	assert.NoError(t, err, "put should be successful with key shortLived")
+
+	var buf bytes.Buffer
+	err = cache.SaveTo(&buf)
+	assert.NoError(t, err, "save should be successful")
+	snapshot := buf.Bytes()
+
+	t.Run("loaded sweep should contain saved entries", func(t *testing.T) {
+		loaded, err := LoadFrom(bytes.NewReader(snapshot), cfg)
+		assert.NoError(t, err, "load should be successful")
+		defer loaded.Close()
+
+		for key, val := range expected {
+			actualVal, err := loaded.Get(key)
+			assert.NoErrorf(t, err, "get should be successful for %s", key)
+			assert.Equalf(t, val, actualVal, "expected %s, got %s", val, actualVal)
+		}
+
+		_, err = loaded.Get("key_0")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		ecount := loaded.EntriesCount()
+		assert.Equalf(t, len(expected)+1, ecount, "expected entries count %d, got %d",
+			len(expected)+1, ecount)
+
+		// The short lived entry keeps its original expiry.
+		time.Sleep(150 * time.Millisecond)
+
+		_, err = loaded.Get("shortLived")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+
+	t.Run("corrupted snapshot should fail to load", func(t *testing.T) {
+		corrupted := make([]byte, len(snapshot))
+		copy(corrupted, snapshot)
+		corrupted[len(corrupted)/2] ^= 0xff
+
+		_, err := LoadFrom(bytes.NewReader(corrupted), cfg)
+		assert.EqualErrorf(t, err, ErrInvalidSnapshot.Error(),
+			"expected err %s, got %s", ErrInvalidSnapshot, err)
+	})
+
+	t.Run("snapshot with bad checksum should put no entries", func(t *testing.T) {
+		corrupted := make([]byte, len(snapshot))
+		copy(corrupted, snapshot)
+		corrupted[len(corrupted)-1] ^= 0xff
+
+		fresh := New(cfg)
+		defer fresh.Close()
+
+		err := fresh.loadSnapshot(bytes.NewReader(corrupted))
+		assert.EqualErrorf(t, err, ErrInvalidSnapshot.Error(),
+			"expected err %s, got %s", ErrInvalidSnapshot, err)
+
+		_, err = fresh.Get("key_1")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+
+	t.Run("truncated snapshot should fail to load", func(t *testing.T) {
+		_, err := LoadFrom(bytes.NewReader(snapshot[:len(snapshot)-6]), cfg)
+		assert.EqualErrorf(t, err, ErrInvalidSnapshot.Error(),
+			"expected err %s, got %s", ErrInvalidSnapshot, err)
+	})
+
+	t.Run("snapshot of unknown version should fail to load", func(t *testing.T) {
+		future := make([]byte, len(snapshot))
+		copy(future, snapshot)
+		future[4] = snapshotVersion + 1
+
+		_, err := LoadFrom(bytes.NewReader(future), cfg)
+		assert.EqualErrorf(t, err, ErrSnapshotVersion.Error(),
+			"expected err %s, got %s", ErrSnapshotVersion, err)
+	})
+}
+
+func TestSweep_Stats(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 2, MaxShardSize: defaultShardSize, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	err := cache.Put("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "put should be successful")
+
+	err = cache.PutWithTTL("raichu", []byte("valueofraichu"), time.Millisecond)
+	assert.NoError(t, err, "put should be successful")
+
+	_, _ = cache.Get("pikachu")
+	_, _ = cache.Get("pikachu")
+	_, _ = cache.Get("bulbasaur")
+
+	_ = cache.Put("tooLarge", make([]byte, defaultMaxEntrySize+1))
+	_ = cache.PutWithTTL("invalidTTL", []byte("value"), -time.Second)
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = cache.cleanupExpiredEntries()
+	assert.NoError(t, err, "cleanup should be successful")
+
+	st := cache.Stats()
+	assert.Equalf(t, uint64(2), st.Hits, "expected hits %d, got %d", 2, st.Hits)
+	assert.Equalf(t, uint64(1), st.Misses, "expected misses %d, got %d", 1, st.Misses)
+	assert.Equalf(t, uint64(1), st.Expirations, "expected expirations %d, got %d",
+		1, st.Expirations)
+	assert.Equalf(t, uint64(1), st.PutFailures.TooLarge, "expected too large failures %d, got %d",
+		1, st.PutFailures.TooLarge)
+	assert.Equalf(t, uint64(1), st.PutFailures.InvalidTTL, "expected invalid ttl failures %d, got %d",
+		1, st.PutFailures.InvalidTTL)
+
+	assert.Equalf(t, 2, len(st.Shards), "expected stats of %d shards, got %d", 2, len(st.Shards))
+
+	bytesUsed := 0
+	for _, shs := range st.Shards {
+		assert.Equalf(t, defaultShardSize, shs.Capacity, "expected capacity %d, got %d",
+			defaultShardSize, shs.Capacity)
+		bytesUsed += shs.BytesUsed
+	}
+
+	expectedBytesUsed := entry.FrameLen([]byte("pikachu"), []byte("valueofpikachu"))
+	assert.Equalf(t, expectedBytesUsed, bytesUsed, "expected bytes used %d, got %d",
+		expectedBytesUsed, bytesUsed)
+}
+
+func TestSweepOnRemove(t *testing.T) {
+	type removed struct {
+		key    string
+		value  string
+		reason RemoveReason
+	}
+
+	var mu sync.Mutex
+	var removals []removed
+
+	newCache := func() *Sweep {
+		var cache *Sweep
+		cache = New(Configuration{
+			ShardsCount:     1,
+			MaxShardSize:    defaultShardSize,
+			EvictionPolicy:  EvictFIFO,
+			CleanupInterval: time.Hour,
+			OnRemove: func(key string, value []byte, reason RemoveReason) {
+				// The shard lock must not be held while the callback runs.
+				_, _ = cache.Get(key)
+
+				mu.Lock()
+				defer mu.Unlock()
+				removals = append(removals, removed{key, string(value), reason})
+			},
+		})
+
+		return cache
+	}
+
+	takeRemovals := func() []removed {
+		mu.Lock()
+		defer mu.Unlock()
+
+		r := removals
+		removals = nil
+		return r
+	}
+
+	cache := newCache()
+	defer cache.Close()
+
+	t.Run("replaced entry should be reported", func(t *testing.T) {
+		_ = cache.Put("pikachu", []byte("old"))
+		_ = cache.Put("pikachu", []byte("new"))
+
+		assert.Equal(t, []removed{{"pikachu", "old", Replaced}}, takeRemovals())
+	})
+
+	t.Run("deleted entry should be reported", func(t *testing.T) {
+		_ = cache.Delete("pikachu")
+
+		assert.Equal(t, []removed{{"pikachu", "new", Deleted}}, takeRemovals())
+	})
+
+	t.Run("expired entry should be reported", func(t *testing.T) {
+		_ = cache.PutWithTTL("raichu", []byte("short"), time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+
+		_, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		assert.Equal(t, []removed{{"raichu", "short", Expired}}, takeRemovals())
+	})
+
+	t.Run("entry displaced by a colliding key should be reported as evicted", func(t *testing.T) {
+		_ = cache.Put("pikachu", []byte("valueofpikachu"))
+
+		// Put "raichu" with the hash of "pikachu" to simulate a collision.
+		err := cache.put(entry.Entry{
+			HashedKey: cache.hashKey("pikachu"),
+			Timestamp: time.Now().UnixNano(),
+			Key:       []byte("raichu"),
+			Value:     []byte("valueofraichu"),
+		}, nil)
+		assert.NoError(t, err, "put should be successful")
+
+		assert.Equal(t, []removed{{"pikachu", "valueofpikachu", Evicted}}, takeRemovals())
+	})
+
+	t.Run("evicted entries should be reported", func(t *testing.T) {
+		cache := newCache()
+		defer cache.Close()
+
+		val := make([]byte, 64)
+		for i := 0; i < 100; i++ {
+			_ = cache.Put(fmt.Sprintf("key_%d", i), val)
+		}
+
+		r := takeRemovals()
+		assert.NotEmpty(t, r, "evictions should be reported")
+
+		for i, rm := range r {
+			assert.Equalf(t, fmt.Sprintf("key_%d", i), rm.key, "expected key %s, got %s",
+				fmt.Sprintf("key_%d", i), rm.key)
+			assert.Equalf(t, Evicted, rm.reason, "expected reason %s, got %s",
+				Evicted, rm.reason)
+		}
+	})
+}
+
+func TestSweep_GetStale(t *testing.T) {
+	cache := New(Configuration{
+		ShardsCount:      1,
+		CleanupInterval:  time.Hour,
+		StaleGracePeriod: 200 * time.Millisecond,
+	})
+	defer cache.Close()
+
+	err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), 50*time.Millisecond)
+	assert.NoError(t, err, "put should be successful")
+
+	val, stale, err := cache.GetStale("pikachu")
+	assert.NoError(t, err, "get stale should be successful")
+	assert.False(t, stale, "value should be fresh")
+	assert.Equalf(t, []byte("valueofpikachu"), val, "expected %s, got %s", "valueofpikachu", val)
+
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("expired entry within grace period should be stale", func(t *testing.T) {
+		_, err := cache.Get("pikachu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		// Cleanup keeps entries within their grace period.
+		_, err = cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+
+		val, stale, err := cache.GetStale("pikachu")
+		assert.NoError(t, err, "get stale should be successful")
+		assert.True(t, stale, "value should be stale")
+		assert.Equalf(t, []byte("valueofpikachu"), val, "expected %s, got %s", "valueofpikachu", val)
+	})
+
+	t.Run("expired entry past grace period should be missing", func(t *testing.T) {
+		time.Sleep(200 * time.Millisecond)
+
+		_, _, err := cache.GetStale("pikachu")
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+
+		n, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+		assert.Equalf(t, 1, n, "expected %d expired entries, got %d", 1, n)
+	})
+}
+
+func TestSweepGrowth(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, MaxShardSize: 8 * 1024 * 1024,
+		CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	// Deleting and cleaning up the oldest keys moves the front of the
+	// queue, so later grows relocate frames wrapped around its end.
+	live := make(map[string]bool)
+	next := 0
+	for round := 0; round < 8; round++ {
+		for i := 0; i < 1000*(round+1); i++ {
+			key := fmt.Sprintf("pokemon%d", next)
+			next++
+
+			err := cache.Put(key, []byte("valueof"+key))
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+			live[key] = true
+		}
+
+		deleted := 0
+		for i := 0; deleted < len(live)/2; i++ {
+			key := fmt.Sprintf("pokemon%d", i)
+			if !live[key] {
+				continue
+			}
+
+			err := cache.Delete(key)
+			assert.NoErrorf(t, err, "delete should be successful with key %s", key)
+			delete(live, key)
+			deleted++
+		}
+
+		_, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+	}
+
+	assert.Truef(t, cache.shards[0].usage().Capacity > 64*1024,
+		"queue should have grown, capacity %d", cache.shards[0].usage().Capacity)
+
+	for key := range live {
+		val, err := cache.Get(key)
+		assert.NoErrorf(t, err, "get should be successful for %s", key)
+		assert.Equalf(t, []byte("valueof"+key), val, "expected %s, got %s", "valueof"+key, val)
+	}
+}
+
+func TestSweepOverwrite(t *testing.T) {
+	t.Run("expired overwritten frame should not remove newer value", func(t *testing.T) {
+		cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+		defer cache.Close()
+
+		err := cache.PutWithTTL("pikachu", []byte("valueofpikachu"), 50*time.Millisecond)
+		assert.NoError(t, err, "put should be successful with key pikachu")
+
+		err = cache.PutWithTTL("pikachu", []byte("newvalueofpikachu"), time.Hour)
+		assert.NoError(t, err, "put should be successful with key pikachu")
+		assert.Equalf(t, 1, cache.EntriesCount(), "expected %d entries, got %d", 1, cache.EntriesCount())
+
+		time.Sleep(100 * time.Millisecond)
+
+		n, err := cache.cleanupExpiredEntries()
+		assert.NoError(t, err, "cleanup should be successful")
+		assert.Equalf(t, 0, n, "expected %d expired entries, got %d", 0, n)
+
+		val, err := cache.Get("pikachu")
+		assert.NoError(t, err, "get should be successful for pikachu")
+		assert.Equalf(t, []byte("newvalueofpikachu"), val, "expected %s, got %s",
+			"newvalueofpikachu", val)
+		assert.Equalf(t, 1, cache.EntriesCount(), "expected %d entries, got %d", 1, cache.EntriesCount())
+	})
+
+	t.Run("evicted overwritten frame should not remove newer value", func(t *testing.T) {
+		var evicted []string
+		cache := New(Configuration{ShardsCount: 1, MaxShardSize: 4 * 1024, CleanupInterval: time.Hour,
+			EvictionPolicy: EvictFIFO,
+			OnRemove: func(key string, value []byte, reason RemoveReason) {
+				if reason == Evicted {
+					evicted = append(evicted, key)
+				}
+			}})
+		defer cache.Close()
+
+		val := make([]byte, 256)
+		for _, key := range []string{"pikachu", "raichu", "pikachu"} {
+			err := cache.Put(key, val)
+			assert.NoErrorf(t, err, "put should be successful with key %s", key)
+		}
+
+		for i := 0; len(evicted) == 0; i++ {
+			err := cache.Put(fmt.Sprintf("pokemon%d", i), val)
+			assert.NoError(t, err, "put should be successful")
+		}
+
+		assert.Equalf(t, []string{"raichu"}, evicted, "expected evicted %v, got %v",
+			[]string{"raichu"}, evicted)
+
+		_, err := cache.Get("pikachu")
+		assert.NoError(t, err, "get should be successful for pikachu")
+		assert.Equalf(t, len(cache.shards[0].hashIndexBucket), cache.EntriesCount(),
+			"expected %d entries, got %d", len(cache.shards[0].hashIndexBucket), cache.EntriesCount())
+	})
+}
+
+func TestSweepKeyCollision(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1})
+	defer cache.Close()
+
+	err := cache.Put("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "put should be successful")
+
+	// Simulate a key colliding with "pikachu" by looking it up with
+	// the hash of "pikachu".
+	keyHash := cache.hashKey("pikachu")
+	sh := cache.shards[cache.getShardIndex(keyHash)]
+
+	_, err = sh.get(keyHash, []byte("raichu"), time.Now().UnixNano())
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+
+	err = sh.del(keyHash, []byte("raichu"), time.Now().UnixNano())
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+
+	actualVal, err := cache.Get("pikachu")
+	assert.NoError(t, err, "get should be successful")
+	assert.Equalf(t, []byte("valueofpikachu"), actualVal, "expected %s, got %s",
+		"valueofpikachu", actualVal)
+
+	collisions := cache.Stats().Collisions
+	assert.Equalf(t, uint64(2), collisions, "expected collisions %d, got %d",
+		2, collisions)
+}
+
 func TestSweep_Close(t *testing.T) {
 	cache := Default()
 	_ = cache.Close()
@@ -0,0 +1,130 @@
+package sweep
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	"github.com/ataul443/sweep/internal/entry"
+)
+
+// View calls fn with the value associated with the key, without copying
+// it out of the sweep. The value is only valid until fn returns and must
+// not be modified. fn runs under the read lock of the key's shard, so it
+// must be quick and must not write to the sweep itself. View returns the
+// error fn returns.
+func (s *Sweep) View(key string, fn func(val []byte) error) error {
+	if s.isClosed() {
+		return ErrClosed
+	}
+
+	now := time.Now().UnixNano()
+	keyHash := s.hashKey(key)
+	shardAllotted := s.shards[s.getShardIndex(keyHash)]
+
+	var timestamp, expiry int64
+	err := shardAllotted.view(keyHash, []byte(key), now, func(frame entry.Frame) error {
+		val, err := entry.ValView(frame)
+		if err != nil {
+			return err
+		}
+
+		if timestamp, err = entry.TimestampFromFrame(frame); err != nil {
+			return err
+		}
+
+		if expiry, err = entry.ExpiryFromFrame(frame); err != nil {
+			return err
+		}
+
+		return fn(val)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.maybeRefresh(key, timestamp, expiry, now)
+	return nil
+}
+
+// GetInto appends the value associated with the key to dst and returns
+// the extended buffer. Unlike Get, it doesn't allocate if dst has room
+// for the value.
+func (s *Sweep) GetInto(key string, dst []byte) ([]byte, error) {
+	err := s.View(key, func(val []byte) error {
+		dst = append(dst, val...)
+		return nil
+	})
+
+	return dst, err
+}
+
+// view calls fn with the frame of the key's live entry under the read
+// lock, and returns the error fn returns.
+func (sh *shard) view(hashedKey uint64, key []byte, now int64, fn func(frame entry.Frame) error) error {
+	if sh.admission != nil {
+		sh.admission.Increment(hashedKey)
+	}
+
+	idx, referenced, err := sh.viewRLocked(hashedKey, key, now, fn)
+	if err != nil {
+		return err
+	}
+
+	if sh.evictionPolicy == EvictCLOCK && !referenced {
+		sh.markReferenced(hashedKey, idx)
+	}
+
+	return nil
+}
+
+// viewRLocked runs viewLocked under the read lock, releasing it even if
+// fn panics.
+func (sh *shard) viewRLocked(hashedKey uint64, key []byte, now int64,
+	fn func(frame entry.Frame) error) (int, bool, error) {
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	return sh.viewLocked(hashedKey, key, now, fn)
+}
+
+func (sh *shard) viewLocked(hashedKey uint64, key []byte, now int64,
+	fn func(frame entry.Frame) error) (int, bool, error) {
+
+	idx, ok := sh.hashIndexBucket[hashedKey]
+	if !ok {
+		atomic.AddUint64(&sh.stats.misses, 1)
+		return 0, false, ErrEntryNotFound
+	}
+
+	frame, err := sh.queue.PeekAt(idx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	storedKey, err := entry.KeyView(frame)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !bytes.Equal(storedKey, key) {
+		atomic.AddUint64(&sh.stats.collisions, 1)
+		atomic.AddUint64(&sh.stats.misses, 1)
+		return 0, false, ErrEntryNotFound
+	}
+
+	expired, err := entry.ExpiredAt(frame, now)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if expired {
+		atomic.AddUint64(&sh.stats.expiredReads, 1)
+		atomic.AddUint64(&sh.stats.misses, 1)
+		return 0, false, ErrEntryNotFound
+	}
+
+	atomic.AddUint64(&sh.stats.hits, 1)
+	return idx, frame.IsReferenced(), fn(frame)
+}
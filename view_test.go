@@ -0,0 +1,102 @@
+package sweep
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweep_GetInto(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	err := cache.Put("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "put should be successful with key pikachu")
+
+	buf, err := cache.GetInto("pikachu", []byte("prefix:"))
+	assert.NoError(t, err, "get into should be successful")
+	assert.Equalf(t, []byte("prefix:valueofpikachu"), buf, "expected %s, got %s",
+		"prefix:valueofpikachu", buf)
+
+	buf, err = cache.GetInto("raichu", buf[:0])
+	assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+		"expected err %s, got %s", ErrEntryNotFound, err)
+	assert.Empty(t, buf, "buffer should be left as it is")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = cache.GetInto("pikachu", buf[:0])
+	})
+	assert.Equalf(t, float64(0), allocs, "expected %d allocations, got %f", 0, allocs)
+}
+
+func TestSweep_View(t *testing.T) {
+	cache := New(Configuration{ShardsCount: 1, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	err := cache.Put("pikachu", []byte("valueofpikachu"))
+	assert.NoError(t, err, "put should be successful with key pikachu")
+
+	t.Run("value should be viewed in place", func(t *testing.T) {
+		err := cache.View("pikachu", func(val []byte) error {
+			assert.Equalf(t, []byte("valueofpikachu"), val, "expected %s, got %s", "valueofpikachu", val)
+			return nil
+		})
+		assert.NoError(t, err, "view should be successful")
+
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = cache.View("pikachu", func(val []byte) error {
+				return nil
+			})
+		})
+		assert.Equalf(t, float64(0), allocs, "expected %d allocations, got %f", 0, allocs)
+	})
+
+	t.Run("error of callback should be returned", func(t *testing.T) {
+		errView := errors.New("view failed")
+
+		err := cache.View("pikachu", func(val []byte) error {
+			return errView
+		})
+		assert.EqualErrorf(t, err, errView.Error(), "expected err %s, got %s", errView, err)
+	})
+
+	t.Run("panicking callback should not leave shard locked", func(t *testing.T) {
+		func() {
+			defer func() {
+				assert.NotNil(t, recover(), "view should panic")
+			}()
+
+			_ = cache.View("pikachu", func(val []byte) error {
+				panic("view failed")
+			})
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			err := cache.Put("raichu", []byte("valueofraichu"))
+			assert.NoError(t, err, "put should be successful with key raichu")
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("put should not block after a panicking view")
+		}
+
+		err := cache.Delete("raichu")
+		assert.NoError(t, err, "delete should be successful with key raichu")
+	})
+
+	t.Run("missing key should not call callback", func(t *testing.T) {
+		err := cache.View("raichu", func(val []byte) error {
+			t.Error("callback should not be called for a missing key")
+			return nil
+		})
+		assert.EqualErrorf(t, err, ErrEntryNotFound.Error(),
+			"expected err %s, got %s", ErrEntryNotFound, err)
+	})
+}